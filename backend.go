@@ -0,0 +1,56 @@
+package libmacouflage
+
+import (
+	"net"
+)
+
+// Link is a snapshot of a network interface as reported by a LinkBackend.
+type Link struct {
+	Index        int
+	Name         string
+	MTU          int
+	Flags        net.Flags
+	HardwareAddr net.HardwareAddr
+	// PermHardwareAddr is only filled in when the backend can report it
+	PermHardwareAddr net.HardwareAddr
+	// HardwareType is the ARPHRD type, zero when the backend cannot tell
+	HardwareType uint16
+	OperState    OperState
+	Carrier      bool
+}
+
+// LinkBackend is the mechanism used to query interfaces and change their
+// hardware address.
+type LinkBackend interface {
+	Links() ([]Link, error)
+	LinkByName(name string) (Link, error)
+	LinkByIndex(index int) (Link, error)
+	SetHardwareAddr(link Link, mac net.HardwareAddr) error
+	SetAdminState(link Link, up bool) error
+}
+
+var linkBackend LinkBackend = NetlinkBackend{}
+
+// SetLinkBackend replaces the backend used by the package level functions.
+func SetLinkBackend(backend LinkBackend) {
+	linkBackend = backend
+}
+
+// GetLinkBackend returns the backend used by the package level functions.
+func GetLinkBackend() LinkBackend {
+	return linkBackend
+}
+
+func (l Link) IsUp() bool {
+	return l.Flags&net.FlagUp != 0
+}
+
+func (l Link) Interface() net.Interface {
+	return net.Interface{
+		Index:        l.Index,
+		MTU:          l.MTU,
+		Name:         l.Name,
+		HardwareAddr: l.HardwareAddr,
+		Flags:        l.Flags,
+	}
+}
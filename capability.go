@@ -0,0 +1,222 @@
+package libmacouflage
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// Capability is a Linux capability number.
+type Capability int
+
+const (
+	CAP_NET_ADMIN Capability = 12
+	CAP_SYS_ADMIN Capability = 21
+)
+
+const _LINUX_CAPABILITY_VERSION_3 = 0x20080522
+
+// ioctls on namespace files, from linux/nsfs.h
+const (
+	NS_GET_USERNS    = 0xb701
+	NS_GET_PARENT    = 0xb702
+	NS_GET_OWNER_UID = 0xb704
+)
+
+// PrivilegeBackend is implemented by backends that decide for themselves
+// whether the caller may change their links.
+type PrivilegeBackend interface {
+	HasNetAdmin() (bool, error)
+}
+
+// MissingCapabilityError is returned when the caller lacks a capability
+// in the user namespace that owns the target network namespace.
+type MissingCapabilityError struct {
+	Capability Capability
+	Interface  string
+	NetNS      string
+}
+
+type capHeader struct {
+	version uint32
+	pid     int32
+}
+
+type capData struct {
+	effective   uint32
+	permitted   uint32
+	inheritable uint32
+}
+
+func (c Capability) String() string {
+	switch c {
+	case CAP_NET_ADMIN:
+		return "CAP_NET_ADMIN"
+	case CAP_SYS_ADMIN:
+		return "CAP_SYS_ADMIN"
+	}
+	return fmt.Sprintf("capability %d", int(c))
+}
+
+func (e MissingCapabilityError) Error() string {
+	return fmt.Sprintf("Insufficient privileges to set MAC on %s, %s is missing in network namespace %s",
+		e.Interface, e.Capability, e.NetNS)
+}
+
+// HasCapability reports whether the calling thread has c in its effective
+// set, read with capget or from /proc/self/status if that fails.
+func HasCapability(c Capability) (result bool, err error) {
+	effective, err := effectiveCapabilities()
+	if err != nil {
+		return
+	}
+	result = effective&(1<<uint(c)) != 0
+	return
+}
+
+func HasNetAdmin() (result bool, err error) {
+	return defaultHandle().HasNetAdmin()
+}
+
+// HasNetAdmin reports whether the caller may administer the links of the
+// handle's namespace. Like the kernel, it walks from the user namespace
+// owning the network namespace up to the caller's own: a caller that owns
+// one of the namespaces in between holds every capability inside it.
+func (h *Handle) HasNetAdmin() (result bool, err error) {
+	if privilegeBackend, ok := h.backend.(PrivilegeBackend); ok {
+		return privilegeBackend.HasNetAdmin()
+	}
+	hasCap, err := HasCapability(CAP_NET_ADMIN)
+	if err != nil {
+		return
+	}
+	own, err := os.Open("/proc/self/ns/user")
+	if err != nil {
+		// Without user namespaces every namespace belongs to init
+		result = hasCap
+		err = nil
+		return
+	}
+	defer own.Close()
+	var owner int
+	err = h.ns.withFile(func(nsfd int) (err error) {
+		owner, err = nsIoctl(nsfd, NS_GET_USERNS)
+		return
+	})
+	if err != nil {
+		// Kernels before 4.9 cannot tell the owner, assume our own
+		result = hasCap
+		err = nil
+		return
+	}
+	euid := uint32(os.Geteuid())
+	userns := owner
+	defer func() {
+		syscall.Close(userns)
+	}()
+	for {
+		same, serr := sameFile(userns, int(own.Fd()))
+		if serr != nil {
+			err = serr
+			return
+		}
+		if same {
+			result = hasCap
+			return
+		}
+		parent, perr := nsIoctl(userns, NS_GET_PARENT)
+		if perr != nil {
+			// EPERM means the parent is outside our namespace, the target
+			// is not below us and we hold nothing in it
+			return
+		}
+		parentIsOwn, serr := sameFile(parent, int(own.Fd()))
+		if serr != nil {
+			syscall.Close(parent)
+			err = serr
+			return
+		}
+		if parentIsOwn {
+			var ownerUid uint32
+			_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(userns), NS_GET_OWNER_UID, uintptr(unsafe.Pointer(&ownerUid)))
+			if errno == 0 && ownerUid == euid {
+				syscall.Close(parent)
+				result = true
+				return
+			}
+		}
+		syscall.Close(userns)
+		userns = parent
+	}
+}
+
+func (h *Handle) checkNetAdmin(name string) (err error) {
+	result, err := h.HasNetAdmin()
+	if err != nil {
+		return
+	}
+	if !result {
+		err = MissingCapabilityError{CAP_NET_ADMIN, name, h.ns.String()}
+	}
+	return
+}
+
+func effectiveCapabilities() (effective uint64, err error) {
+	hdr := capHeader{version: _LINUX_CAPABILITY_VERSION_3}
+	var data [2]capData
+	_, _, errno := syscall.Syscall(syscall.SYS_CAPGET, uintptr(unsafe.Pointer(&hdr)), uintptr(unsafe.Pointer(&data[0])), 0)
+	if errno == 0 {
+		effective = uint64(data[0].effective) | uint64(data[1].effective)<<32
+		return
+	}
+	return statusCapabilities("CapEff")
+}
+
+func statusCapabilities(field string) (caps uint64, err error) {
+	file, err := os.Open("/proc/self/status")
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == field+":" {
+			caps, err = strconv.ParseUint(fields[1], 16, 64)
+			return
+		}
+	}
+	err = scanner.Err()
+	if err == nil {
+		err = fmt.Errorf("%s not found in /proc/self/status", field)
+	}
+	return
+}
+
+func nsIoctl(fd int, req uintptr) (nsfd int, err error) {
+	r, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, 0)
+	if errno != 0 {
+		err = syscall.Errno(errno)
+		return
+	}
+	nsfd = int(r)
+	return
+}
+
+func sameFile(a int, b int) (same bool, err error) {
+	var sa, sb syscall.Stat_t
+	err = syscall.Fstat(a, &sa)
+	if err != nil {
+		return
+	}
+	err = syscall.Fstat(b, &sb)
+	if err != nil {
+		return
+	}
+	same = sa.Dev == sb.Dev && sa.Ino == sb.Ino
+	return
+}
@@ -0,0 +1,43 @@
+package libmacouflage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HasCapability_1(t *testing.T) {
+	fromCapget, err := effectiveCapabilities()
+	assert.NoError(t, err)
+	fromStatus, err := statusCapabilities("CapEff")
+	assert.NoError(t, err)
+	assert.Equal(t, fromStatus, fromCapget)
+}
+
+func Test_HasNetAdmin_1(t *testing.T) {
+	hasCap, err := HasCapability(CAP_NET_ADMIN)
+	assert.NoError(t, err)
+	result, err := HasNetAdmin()
+	assert.NoError(t, err)
+	assert.Equal(t, hasCap, result, "Own namespace should only depend on the effective set")
+}
+
+func Test_HasNetAdmin_2(t *testing.T) {
+	h, err := NewHandleAt(NetNSByPid(os.Getpid()))
+	assert.NoError(t, err)
+	if err != nil {
+		return
+	}
+	expected, err := HasNetAdmin()
+	assert.NoError(t, err)
+	result, err := h.HasNetAdmin()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, result)
+}
+
+func Test_MissingCapabilityError_1(t *testing.T) {
+	err := MissingCapabilityError{CAP_NET_ADMIN, "eth0", "current"}
+	assert.Contains(t, err.Error(), "CAP_NET_ADMIN")
+	assert.Contains(t, err.Error(), "eth0")
+}
@@ -0,0 +1,311 @@
+// Command macchanger is a drop-in replacement for GNU MAC Changer built on
+// libmacouflage. It takes the same options and prints the same output, so
+// that scripts written for macchanger keep working.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/subgraph/libmacouflage"
+)
+
+// Exit codes of macchanger
+const (
+	exitOK    = 0
+	exitError = 1
+)
+
+const usage = `GNU MAC Changer
+Usage: macchanger [options] device
+
+  -h,  --help                   Print this help
+  -V,  --version                Print version and exit
+  -s,  --show                   Print the MAC address and exit
+  -e,  --ending                 Don't change the vendor bytes
+  -a,  --another                Set random vendor MAC of the same kind
+  -A                            Set random vendor MAC of any kind
+  -p,  --permanent              Reset to original, permanent hardware MAC
+  -r,  --random                 Set fully random MAC
+  -l,  --list[=keyword]         Print known vendors
+  -b,  --bia                    Pretend to be a burned-in-address
+  -m,  --mac=XX:XX:XX:XX:XX:XX
+       --mac XX:XX:XX:XX:XX:XX  Set the MAC XX:XX:XX:XX:XX:XX
+
+Report bugs to https://github.com/alobbs/macchanger/issues
+`
+
+// version tells this tool from macchanger, unlike the rest of the output
+const version = `GNU MAC Changer 1.7.0 (libmacouflage)
+`
+
+type options struct {
+	help      bool
+	version   bool
+	show      bool
+	ending    bool
+	another   bool
+	any       bool
+	permanent bool
+	random    bool
+	list      bool
+	keyword   string
+	bia       bool
+	mac       string
+	device    string
+}
+
+var longOptions = map[string]byte{
+	"help":      'h',
+	"version":   'V',
+	"show":      's',
+	"ending":    'e',
+	"another":   'a',
+	"permanent": 'p',
+	"random":    'r',
+	"list":      'l',
+	"bia":       'b',
+	"mac":       'm',
+}
+
+func main() {
+	h := libmacouflage.NewHandle(libmacouflage.GetLinkBackend())
+	os.Exit(run(h, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(h *libmacouflage.Handle, args []string, stdout io.Writer, stderr io.Writer) int {
+	opts, err := parseArgs(args)
+	if err != nil {
+		fmt.Fprintf(stderr, "macchanger: %v\n", err)
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	switch {
+	case opts.help:
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	case opts.version:
+		fmt.Fprint(stdout, version)
+		return exitOK
+	case opts.list:
+		printList(stdout, opts.keyword)
+		return exitOK
+	case opts.device == "":
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	current, err := h.GetCurrentMac(opts.device)
+	if err != nil {
+		fmt.Fprintf(stderr, "[ERROR] Set device name: %s\n", strerror(err))
+		return exitError
+	}
+	// macchanger shows the current address when the permanent one is
+	// unknown
+	permanent, err := h.GetPermanentMac(opts.device)
+	if err != nil {
+		permanent = current
+	}
+	printMac(stdout, "Current MAC:   ", current)
+	printMac(stdout, "Permanent MAC: ", permanent)
+	var strategy libmacouflage.Strategy
+	switch {
+	case opts.show:
+		return exitOK
+	case opts.mac != "":
+		mac, perr := net.ParseMAC(opts.mac)
+		if perr != nil {
+			fmt.Fprintf(stderr, "[ERROR] Incorrect MAC address: %s\n", opts.mac)
+			return exitError
+		}
+		strategy = libmacouflage.SpecificStrategy(mac)
+	case opts.permanent:
+		strategy = libmacouflage.SpecificStrategy(permanent)
+	case opts.random:
+		strategy = libmacouflage.RandomStrategy(opts.bia)
+	case opts.ending:
+		strategy = endingStrategy()
+	case opts.another:
+		strategy, err = libmacouflage.LookupStrategy(libmacouflage.ModeAnother)
+	case opts.any:
+		strategy, err = libmacouflage.LookupStrategy(libmacouflage.ModeAny)
+	default:
+		return exitOK
+	}
+	if err == nil {
+		_, err = h.Spoof(context.Background(), opts.device, strategy)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "[ERROR] Could not change MAC: interface up or insufficient permissions: %s\n", strerror(err))
+		return exitError
+	}
+	mac, err := h.GetCurrentMac(opts.device)
+	if err != nil {
+		fmt.Fprintf(stderr, "[ERROR] Set device name: %s\n", strerror(err))
+		return exitError
+	}
+	printMac(stdout, "New MAC:       ", mac)
+	if libmacouflage.CompareMacs(current, mac) {
+		fmt.Fprint(stdout, "It's the same MAC!!\n")
+	}
+	return exitOK
+}
+
+// endingStrategy randomizes the bytes after the vendor ones and leaves the
+// first three bytes as they are, burned-in address bit included, the way
+// macchanger -e does whatever -b says
+func endingStrategy() libmacouflage.Strategy {
+	return libmacouflage.NewMode("Same Vendor", "Don't change the vendor bytes", "e", libmacouflage.ModeEnding,
+		func(current net.HardwareAddr, permanent net.HardwareAddr) (mac net.HardwareAddr, err error) {
+			mac, err = libmacouflage.SameVendorStrategy(true).Generate(current, permanent)
+			if err == nil && len(mac) == len(current) && len(mac) >= 3 {
+				copy(mac[:3], current[:3])
+			}
+			return
+		})
+}
+
+// parseArgs parses args the way getopt_long does for macchanger: short
+// options may be grouped, -m takes the next argument unless attached, and
+// the optional keyword of -l must be attached
+func parseArgs(args []string) (opts options, err error) {
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			operands = append(operands, args[i+1:]...)
+			i = len(args)
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			option, ok := longOptions[name]
+			if !ok {
+				err = fmt.Errorf("unrecognized option '%s'", arg)
+				return
+			}
+			switch {
+			case option == 'm' && !hasValue:
+				if i+1 == len(args) {
+					err = fmt.Errorf("option '--mac' requires an argument")
+					return
+				}
+				i++
+				value = args[i]
+			case hasValue && option != 'm' && option != 'l':
+				err = fmt.Errorf("option '--%s' doesn't allow an argument", name)
+				return
+			}
+			opts.set(option, value)
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for j := 1; j < len(arg); j++ {
+				option := arg[j]
+				if !strings.ContainsRune("hVseaAprlbm", rune(option)) {
+					err = fmt.Errorf("invalid option -- '%c'", option)
+					return
+				}
+				if option != 'm' && option != 'l' {
+					opts.set(option, "")
+					continue
+				}
+				value := arg[j+1:]
+				if option == 'm' && value == "" {
+					if i+1 == len(args) {
+						err = fmt.Errorf("option requires an argument -- 'm'")
+						return
+					}
+					i++
+					value = args[i]
+				}
+				opts.set(option, value)
+				break
+			}
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	if len(operands) > 0 {
+		opts.device = operands[len(operands)-1]
+	}
+	return
+}
+
+func (opts *options) set(option byte, value string) {
+	switch option {
+	case 'h':
+		opts.help = true
+	case 'V':
+		opts.version = true
+	case 's':
+		opts.show = true
+	case 'e':
+		opts.ending = true
+	case 'a':
+		opts.another = true
+	case 'A':
+		opts.any = true
+	case 'p':
+		opts.permanent = true
+	case 'r':
+		opts.random = true
+	case 'l':
+		opts.list = true
+		opts.keyword = value
+	case 'b':
+		opts.bia = true
+	case 'm':
+		opts.mac = value
+	}
+}
+
+func printMac(w io.Writer, label string, mac net.HardwareAddr) {
+	vendor := "unknown"
+	if len(mac) >= 3 {
+		if oui, err := libmacouflage.FindVendorByHardwareAddr(mac); err == nil {
+			vendor = oui.Vendor
+		}
+	}
+	fmt.Fprintf(w, "%s%s (%s)\n", label, mac, vendor)
+}
+
+// printList prints the vendors whose name contains keyword, wireless ones
+// apart as macchanger does. The numbers are positions in each list.
+func printList(w io.Writer, keyword string) {
+	var misc, wireless []libmacouflage.Oui
+	for _, oui := range libmacouflage.OuiDb {
+		if len(oui.Devices) > 0 && strings.HasPrefix(oui.Devices[0].DeviceType, "oui_wireless") {
+			wireless = append(wireless, oui)
+		} else {
+			misc = append(misc, oui)
+		}
+	}
+	for _, list := range []struct {
+		title string
+		ouis  []libmacouflage.Oui
+	}{{"Misc MACs:", misc}, {"\nWireless MACs:", wireless}} {
+		fmt.Fprintf(w, "%s\nNum    MAC        Vendor\n---    ---        ------\n", list.title)
+		for i, oui := range list.ouis {
+			if keyword != "" && !strings.Contains(strings.ToLower(oui.Vendor), strings.ToLower(keyword)) {
+				continue
+			}
+			fmt.Fprintf(w, "%04d - %s - %s\n", i, strings.ToLower(oui.VendorPrefix), oui.Vendor)
+		}
+	}
+}
+
+// strerror describes err as the C library would, from the errno it wraps
+func strerror(err error) string {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		msg := errno.Error()
+		return strings.ToUpper(msg[:1]) + msg[1:]
+	}
+	if errors.Is(err, libmacouflage.ErrNoSuchInterface) {
+		return "No such device"
+	}
+	return err.Error()
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subgraph/libmacouflage"
+)
+
+func newTestHandle() (*libmacouflage.Handle, *libmacouflage.FakeBackend) {
+	perm, _ := net.ParseMAC(libmacouflage.OuiDb[0].VendorPrefix + ":12:34:56")
+	backend := libmacouflage.NewFakeBackend(
+		libmacouflage.FakeInterface{Name: "eth0", Kind: libmacouflage.KindEthernet, Driver: "e1000e",
+			HardwareAddr: perm, PermHardwareAddr: perm},
+	)
+	return libmacouflage.NewHandle(backend), backend
+}
+
+func runTest(h *libmacouflage.Handle, args ...string) (code int, stdout string, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(h, args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func Test_ParseArgs_1(t *testing.T) {
+	opts, err := parseArgs([]string{"-rb", "eth0"})
+	assert.NoError(t, err)
+	assert.Equal(t, options{random: true, bia: true, device: "eth0"}, opts)
+	opts, err = parseArgs([]string{"--mac", "00:11:22:33:44:55", "eth0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "00:11:22:33:44:55", opts.mac)
+	opts, err = parseArgs([]string{"-m00:11:22:33:44:55", "eth0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "00:11:22:33:44:55", opts.mac)
+	opts, err = parseArgs([]string{"--list=cisco"})
+	assert.NoError(t, err)
+	assert.Equal(t, "cisco", opts.keyword)
+	_, err = parseArgs([]string{"-x", "eth0"})
+	assert.Error(t, err)
+	_, err = parseArgs([]string{"eth0", "-m"})
+	assert.Error(t, err)
+}
+
+func Test_Run_1(t *testing.T) {
+	h, backend := newTestHandle()
+	vendor := libmacouflage.OuiDb[0].Vendor
+	code, out, _ := runTest(h, "-m", "02:11:22:33:44:55", "eth0")
+	assert.Equal(t, exitOK, code)
+	iface, _ := backend.Interface("eth0")
+	perm := iface.PermHardwareAddr.String()
+	assert.Equal(t, "Current MAC:   "+perm+" ("+vendor+")\n"+
+		"Permanent MAC: "+perm+" ("+vendor+")\n"+
+		"New MAC:       02:11:22:33:44:55 (unknown)\n", out)
+	code, out, _ = runTest(h, "-s", "eth0")
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "Current MAC:   02:11:22:33:44:55 (unknown)\n"+
+		"Permanent MAC: "+perm+" ("+vendor+")\n", out)
+	code, out, _ = runTest(h, "-p", "eth0")
+	assert.Equal(t, exitOK, code)
+	assert.True(t, strings.HasSuffix(out, "New MAC:       "+perm+" ("+vendor+")\n"))
+	code, out, _ = runTest(h, "-p", "eth0")
+	assert.Equal(t, exitOK, code)
+	assert.True(t, strings.HasSuffix(out, "It's the same MAC!!\n"))
+}
+
+func Test_Run_2(t *testing.T) {
+	h, backend := newTestHandle()
+	backend.AddDriverRule("e1000e", libmacouflage.RejectWhileUp)
+	iface, _ := backend.Interface("eth0")
+	iface.Up = true
+	backend.AddInterface(iface)
+	code, _, stderr := runTest(h, "-r", "eth0")
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, "[ERROR] Could not change MAC: interface up or insufficient permissions: Device or resource busy\n", stderr)
+	code, _, stderr = runTest(h, "-r", "missing0")
+	assert.Equal(t, exitError, code)
+	assert.Equal(t, "[ERROR] Set device name: No such device\n", stderr)
+	code, out, _ := runTest(h, "-l")
+	assert.Equal(t, exitOK, code)
+	assert.True(t, strings.HasPrefix(out, "Misc MACs:\nNum    MAC        Vendor\n---    ---        ------\n0000 - "))
+	assert.Contains(t, out, "\n\nWireless MACs:\n")
+}
+
+func Test_Run_3(t *testing.T) {
+	h, backend := newTestHandle()
+	iface, _ := backend.Interface("eth0")
+	for _, args := range [][]string{{"-e", "eth0"}, {"-e", "-b", "eth0"}} {
+		code, _, _ := runTest(h, args...)
+		assert.Equal(t, exitOK, code)
+		changed, _ := backend.Interface("eth0")
+		assert.Equal(t, iface.PermHardwareAddr[:3], changed.HardwareAddr[:3])
+		assert.NotEqual(t, iface.PermHardwareAddr, changed.HardwareAddr)
+	}
+}
@@ -0,0 +1,519 @@
+// Command macouflage shows and changes the MAC addresses of network
+// interfaces with libmacouflage.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/subgraph/libmacouflage"
+)
+
+// Exit codes
+const (
+	exitOK = iota
+	exitFailure
+	exitUsage
+	exitNotPermitted
+	exitNoSuchInterface
+	exitInvalidAddress
+	exitRejected
+	exitNoVendor
+)
+
+const usage = `Usage: macouflage [--json] <command> [options] [arguments]
+
+Commands:
+  show [interface...]          Show the current and permanent MACs
+  set <interface> <mac>        Set the MAC
+  random <interface>           Set fully random MAC
+  vendor <interface>           Don't change the vendor bytes
+  another <interface>          Set random vendor MAC of the same kind
+  any <interface>              Set random vendor MAC of any kind
+  popular <interface>          Set random MAC of a popular vendor
+  revert <interface>           Set the permanent MAC again
+  list-vendors [keyword]       List the vendors of the OUI database
+  lookup <mac>                 Show the vendor of a MAC
+  inventory                    Show every interface and its kind
+  rotate <interface...>        Change the MACs periodically until stopped
+
+Options of the commands that change a MAC:
+  --bia          Burned-in address instead of a locally administered one,
+                 not for set
+  --local        Locally administered address, not for set
+  --auto-down    Bring an up interface down for the change
+  --dry-run      Print the new MAC without setting it, not for rotate
+  --timeout d    Give up after d, such as 5s
+
+Options of list-vendors:
+  --popular      List only the popular vendors
+
+Options of rotate:
+  --mode m       Strategy of the rotations, popular by default
+  --interval d   Time between rotations, 1h by default
+  --jitter d     Random time added to the interval
+  --quiet        Rotate only while the link has no carrier
+  --now          Rotate once at start
+  --revert       Set the permanent MACs again when stopped
+
+Exit codes: 0 success, 1 failure, 2 usage, 3 not permitted, 4 no such or
+unsupported interface, 5 invalid address, 6 address refused, 7 no vendor.
+`
+
+// usageError is returned for bad command lines
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+type cli struct {
+	handle *libmacouflage.Handle
+	stdout io.Writer
+	json   bool
+}
+
+// Options of the commands that spoof
+var spoofFlags = []string{"bia", "local", "auto-down", "dry-run", "timeout"}
+
+// commandFlags maps each command to the options it takes besides --json
+var commandFlags = map[string][]string{
+	"show":         nil,
+	"set":          {"auto-down", "dry-run", "timeout"},
+	"random":       spoofFlags,
+	"vendor":       spoofFlags,
+	"another":      spoofFlags,
+	"any":          spoofFlags,
+	"popular":      spoofFlags,
+	"revert":       nil,
+	"list-vendors": {"popular"},
+	"lookup":       nil,
+	"inventory":    nil,
+	"rotate":       {"bia", "local", "auto-down", "timeout", "mode", "interval", "jitter", "quiet", "now", "revert"},
+}
+
+// spoofModes maps the commands that spoof to their strategy
+var spoofModes = map[string]string{
+	"random":  libmacouflage.ModeRandom,
+	"vendor":  libmacouflage.ModeEnding,
+	"another": libmacouflage.ModeAnother,
+	"any":     libmacouflage.ModeAny,
+	"popular": libmacouflage.ModePopular,
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, libmacouflage.NewHandle(libmacouflage.GetLinkBackend()), os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, h *libmacouflage.Handle, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("macouflage", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	c := &cli{handle: h, stdout: stdout}
+	flags.BoolVar(&c.json, "json", false, "")
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	err := c.command(ctx, flags.Arg(0), flags.Args()[1:], stderr)
+	if err != nil {
+		fmt.Fprintf(stderr, "macouflage: %v\n", err)
+	}
+	return exitCode(err)
+}
+
+func (c *cli) command(ctx context.Context, command string, args []string, stderr io.Writer) (err error) {
+	allowed, ok := commandFlags[command]
+	if !ok {
+		return usageError{fmt.Sprintf("unknown command %q", command)}
+	}
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() { fmt.Fprint(stderr, usage) }
+	flags.BoolVar(&c.json, "json", c.json, "")
+	bia := flags.Bool("bia", false, "")
+	local := flags.Bool("local", false, "")
+	autoDown := flags.Bool("auto-down", false, "")
+	dryRun := flags.Bool("dry-run", false, "")
+	timeout := flags.Duration("timeout", 0, "")
+	popular := flags.Bool("popular", false, "")
+	var rotation libmacouflage.Rotation
+	mode := flags.String("mode", libmacouflage.ModePopular, "")
+	flags.DurationVar(&rotation.Interval, "interval", time.Hour, "")
+	flags.DurationVar(&rotation.Jitter, "jitter", 0, "")
+	flags.BoolVar(&rotation.QuietOnly, "quiet", false, "")
+	flags.BoolVar(&rotation.Immediate, "now", false, "")
+	revert := flags.Bool("revert", false, "")
+	err = flags.Parse(args)
+	if err != nil {
+		err = usageError{err.Error()}
+		return
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if err == nil && f.Name != "json" && !contains(allowed, f.Name) {
+			err = usageError{fmt.Sprintf("%s does not take --%s", command, f.Name)}
+		}
+	})
+	if err != nil {
+		return
+	}
+	args = flags.Args()
+	var opts []libmacouflage.SpoofOption
+	if *bia && *local {
+		err = usageError{"--bia and --local exclude each other"}
+		return
+	}
+	if *bia || *local {
+		opts = append(opts, libmacouflage.WithBIA(*bia))
+	}
+	if *autoDown {
+		opts = append(opts, libmacouflage.WithAutoDown())
+	}
+	if *dryRun {
+		opts = append(opts, libmacouflage.WithDryRun())
+	}
+	if *timeout > 0 {
+		opts = append(opts, libmacouflage.WithTimeout(*timeout))
+	}
+	switch command {
+	case "show":
+		return c.show(args)
+	case "set":
+		if len(args) != 2 {
+			return usageError{"set takes an interface and a MAC"}
+		}
+		var mac net.HardwareAddr
+		mac, err = net.ParseMAC(args[1])
+		if err != nil {
+			return libmacouflage.InvalidMacError{Mac: args[1], Err: err}
+		}
+		return c.spoof(ctx, args, libmacouflage.SpecificStrategy(mac), opts)
+	case "random", "vendor", "another", "any", "popular":
+		var strategy libmacouflage.Strategy
+		strategy, err = libmacouflage.LookupStrategy(spoofModes[command])
+		if err != nil {
+			return
+		}
+		return c.spoof(ctx, args, strategy, opts)
+	case "revert":
+		return c.revert(args)
+	case "list-vendors":
+		return c.listVendors(args, *popular)
+	case "lookup":
+		return c.lookup(args)
+	case "inventory":
+		return c.inventory(args)
+	case "rotate":
+		rotation.Options = opts
+		rotation.Strategy, err = libmacouflage.LookupStrategy(*mode)
+		if err != nil {
+			return usageError{err.Error()}
+		}
+		return c.rotate(ctx, args, rotation, *revert)
+	}
+	return
+}
+
+type macInfo struct {
+	Interface       string `json:"interface"`
+	CurrentMac      string `json:"current_mac"`
+	PermanentMac    string `json:"permanent_mac,omitempty"`
+	PermanentSource string `json:"permanent_source,omitempty"`
+	Changed         bool   `json:"changed"`
+	Vendor          string `json:"vendor,omitempty"`
+}
+
+func (c *cli) show(names []string) (err error) {
+	if len(names) == 0 {
+		var ifaces []net.Interface
+		ifaces, err = c.handle.GetInterfaces()
+		if err != nil {
+			return
+		}
+		for _, iface := range ifaces {
+			names = append(names, iface.Name)
+		}
+	}
+	var infos []macInfo
+	for _, name := range names {
+		info := macInfo{Interface: name}
+		var current net.HardwareAddr
+		current, err = c.handle.GetCurrentMac(name)
+		if err != nil {
+			return
+		}
+		info.CurrentMac = current.String()
+		info.Vendor = knownVendor(current)
+		// Interfaces without a known permanent MAC are still shown
+		perm, permErr := c.handle.GetPermanentMacInfo(name)
+		if permErr == nil {
+			info.PermanentMac = perm.HardwareAddr.String()
+			info.PermanentSource = perm.Source.String()
+			info.Changed = !libmacouflage.CompareMacs(current, perm.HardwareAddr)
+		}
+		infos = append(infos, info)
+	}
+	if c.json {
+		return c.writeJSON(infos)
+	}
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Fprintln(c.stdout)
+		}
+		fmt.Fprintf(c.stdout, "%s\n", info.Interface)
+		current, _ := net.ParseMAC(info.CurrentMac)
+		fmt.Fprintf(c.stdout, "  Current MAC:   %s (%s)\n", info.CurrentMac, vendorName(current))
+		if info.PermanentMac == "" {
+			fmt.Fprintf(c.stdout, "  Permanent MAC: unknown\n")
+			continue
+		}
+		perm, _ := net.ParseMAC(info.PermanentMac)
+		fmt.Fprintf(c.stdout, "  Permanent MAC: %s (%s) [%s]\n", info.PermanentMac, vendorName(perm), info.PermanentSource)
+	}
+	return
+}
+
+func (c *cli) spoof(ctx context.Context, args []string, strategy libmacouflage.Strategy, opts []libmacouflage.SpoofOption) (err error) {
+	if len(args) == 0 {
+		return usageError{"missing interface"}
+	}
+	if len(args) > 2 || (len(args) == 2 && strategy.Name() != libmacouflage.ModeSpecific) {
+		return usageError{"too many arguments"}
+	}
+	result, err := c.handle.Spoof(ctx, args[0], strategy, opts...)
+	if err != nil {
+		return
+	}
+	if c.json {
+		return c.writeJSON(result)
+	}
+	fmt.Fprintf(c.stdout, "Current MAC:   %s (%s)\n", result.OldMac, vendorName(result.OldMac))
+	if result.PermanentMac != nil {
+		fmt.Fprintf(c.stdout, "Permanent MAC: %s (%s)\n", result.PermanentMac, vendorName(result.PermanentMac))
+	}
+	label := "New MAC:      "
+	if result.DryRun {
+		label = "Would set MAC:"
+	}
+	fmt.Fprintf(c.stdout, "%s %s (%s)\n", label, result.NewMac, vendorName(result.NewMac))
+	return
+}
+
+func (c *cli) revert(args []string) (err error) {
+	if len(args) != 1 {
+		return usageError{"revert takes an interface"}
+	}
+	err = c.handle.RevertMac(args[0])
+	if err != nil {
+		return
+	}
+	mac, err := c.handle.GetCurrentMac(args[0])
+	if err != nil {
+		return
+	}
+	if c.json {
+		return c.writeJSON(macInfo{Interface: args[0], CurrentMac: mac.String(), Vendor: knownVendor(mac)})
+	}
+	fmt.Fprintf(c.stdout, "%s: reverted to %s (%s)\n", args[0], mac, vendorName(mac))
+	return
+}
+
+func (c *cli) listVendors(args []string, popular bool) (err error) {
+	if len(args) > 1 {
+		return usageError{"list-vendors takes at most one keyword"}
+	}
+	var ouis []libmacouflage.Oui
+	if len(args) == 1 {
+		ouis, err = libmacouflage.FindVendorsByKeyword(args[0])
+	} else {
+		ouis = libmacouflage.OuiDb
+	}
+	if err != nil {
+		return
+	}
+	var matches []libmacouflage.Oui
+	for _, oui := range ouis {
+		if !popular || oui.Popular {
+			matches = append(matches, oui)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("no matching vendor: %w", libmacouflage.ErrNoVendor)
+	}
+	if c.json {
+		return c.writeJSON(matches)
+	}
+	for i, oui := range matches {
+		fmt.Fprintf(c.stdout, "%04d - %s - %s\n", i, strings.ToLower(oui.VendorPrefix), oui.Vendor)
+	}
+	return
+}
+
+func (c *cli) lookup(args []string) (err error) {
+	if len(args) != 1 {
+		return usageError{"lookup takes a MAC"}
+	}
+	oui, err := libmacouflage.FindVendorByMac(args[0])
+	if err != nil {
+		return
+	}
+	if c.json {
+		return c.writeJSON(oui)
+	}
+	fmt.Fprintf(c.stdout, "%s %s\n", strings.ToLower(oui.VendorPrefix), oui.Vendor)
+	for _, device := range oui.Devices {
+		fmt.Fprintf(c.stdout, "  %s: %s\n", device.DeviceType, device.DeviceName)
+	}
+	return
+}
+
+func (c *cli) inventory(args []string) (err error) {
+	if len(args) != 0 {
+		return usageError{"inventory takes no arguments"}
+	}
+	entries, err := c.handle.Inventory()
+	if err != nil {
+		return
+	}
+	if c.json {
+		return c.writeJSON(entries)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "INTERFACE\tKIND\tDRIVER\tSTATE\tCURRENT MAC\tPERMANENT MAC")
+	for _, entry := range entries {
+		state := "down"
+		if entry.Up {
+			state = "up"
+		}
+		if !entry.Allowed {
+			state += ",skipped"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", entry.Interface, entry.Kind, orDash(entry.Driver),
+			state, orDash(entry.CurrentMac.String()), orDash(entry.PermanentMac.String()))
+	}
+	return w.Flush()
+}
+
+func (c *cli) rotate(ctx context.Context, names []string, rotation libmacouflage.Rotation, revert bool) (err error) {
+	if len(names) == 0 {
+		return usageError{"rotate takes at least one interface"}
+	}
+	var rotations []libmacouflage.Rotation
+	for _, name := range names {
+		rotation.Interface = name
+		rotations = append(rotations, rotation)
+	}
+	r := libmacouflage.NewRotator(c.handle, rotations...)
+	r.RevertOnStop = revert
+	r.OnRotate = func(event libmacouflage.RotationEvent) {
+		switch {
+		case c.json:
+			c.writeJSON(rotationEventJSON{event.Interface, event.Time, event.Result, errorText(event.Err)})
+		case event.Err != nil:
+			fmt.Fprintf(c.stdout, "%s %s: %v\n", event.Time.Format(time.RFC3339), event.Interface, event.Err)
+		default:
+			fmt.Fprintf(c.stdout, "%s %s: %s (%s)\n", event.Time.Format(time.RFC3339), event.Interface,
+				event.Result.NewMac, vendorName(event.Result.NewMac))
+		}
+	}
+	return r.Run(ctx)
+}
+
+type rotationEventJSON struct {
+	Interface string                    `json:"interface"`
+	Time      time.Time                 `json:"time"`
+	Result    libmacouflage.SpoofResult `json:"result"`
+	Error     string                    `json:"error,omitempty"`
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func (c *cli) writeJSON(v interface{}) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func vendorName(mac net.HardwareAddr) string {
+	if vendor := knownVendor(mac); vendor != "" {
+		return vendor
+	}
+	return "unknown"
+}
+
+// knownVendor returns the vendor of the longest assignment mac falls in, if
+// any
+func knownVendor(mac net.HardwareAddr) string {
+	if len(mac) < 3 {
+		return ""
+	}
+	oui, err := libmacouflage.FindVendorByHardwareAddr(mac)
+	if err != nil {
+		return ""
+	}
+	return oui.Vendor
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func exitCode(err error) int {
+	var usageErr usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.Is(err, libmacouflage.ErrNotPermitted):
+		return exitNotPermitted
+	case errors.Is(err, libmacouflage.ErrNoSuchInterface), errors.Is(err, libmacouflage.ErrInvalidInterfaceType):
+		return exitNoSuchInterface
+	case errors.Is(err, libmacouflage.ErrInvalidMac), errors.Is(err, libmacouflage.ErrUnsupportedAddress):
+		return exitInvalidAddress
+	case errors.Is(err, libmacouflage.ErrAddressRejected), errors.Is(err, libmacouflage.ErrInterfaceUp):
+		return exitRejected
+	case errors.Is(err, libmacouflage.ErrNoVendor):
+		return exitNoVendor
+	}
+	return exitFailure
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subgraph/libmacouflage"
+)
+
+func newTestHandle() (*libmacouflage.Handle, *libmacouflage.FakeBackend) {
+	perm, _ := net.ParseMAC(libmacouflage.OuiDb[0].VendorPrefix + ":12:34:56")
+	backend := libmacouflage.NewFakeBackend(
+		libmacouflage.FakeInterface{Name: "fake0", Kind: libmacouflage.KindEthernet, Driver: "e1000e",
+			HardwareAddr: perm, PermHardwareAddr: perm},
+		libmacouflage.FakeInterface{Name: "lo", Kind: libmacouflage.KindLoopback,
+			HardwareType: libmacouflage.ARPHRD_LOOPBACK, HardwareAddr: make(net.HardwareAddr, 6)},
+	)
+	return libmacouflage.NewHandle(backend), backend
+}
+
+func runTest(h *libmacouflage.Handle, args ...string) (code int, stdout string, stderr string) {
+	var out, errOut bytes.Buffer
+	code = run(context.Background(), h, args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+func Test_Run_1(t *testing.T) {
+	h, backend := newTestHandle()
+	code, out, _ := runTest(h, "--json", "random", "--local", "fake0")
+	assert.Equal(t, exitOK, code)
+	var result libmacouflage.SpoofResult
+	assert.NoError(t, json.Unmarshal([]byte(out), &result))
+	iface, _ := backend.Interface("fake0")
+	assert.Equal(t, iface.HardwareAddr, result.NewMac)
+	assert.Equal(t, byte(2), result.NewMac[0]&2)
+	code, out, _ = runTest(h, "show", "fake0")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, out, "Current MAC:   "+iface.HardwareAddr.String())
+	code, _, _ = runTest(h, "revert", "fake0")
+	assert.Equal(t, exitOK, code)
+	iface, _ = backend.Interface("fake0")
+	assert.Equal(t, iface.PermHardwareAddr, iface.HardwareAddr)
+	code, out, _ = runTest(h, "set", "--dry-run", "fake0", "00:11:22:33:44:55")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, out, "Would set MAC: 00:11:22:33:44:55")
+}
+
+func Test_Run_2(t *testing.T) {
+	h, backend := newTestHandle()
+	backend.AddDriverRule("e1000e", libmacouflage.RejectLocallyAdministered)
+	for _, c := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{}, exitUsage},
+		{[]string{"bogus"}, exitUsage},
+		{[]string{"random"}, exitUsage},
+		{[]string{"random", "missing0"}, exitNoSuchInterface},
+		{[]string{"random", "lo"}, exitNoSuchInterface},
+		{[]string{"set", "fake0", "00:11:22"}, exitInvalidAddress},
+		{[]string{"random", "--local", "fake0"}, exitRejected},
+		{[]string{"lookup", "ff:ff:fe:00:00:00"}, exitNoVendor},
+		{[]string{"set", "--bia", "fake0", "00:11:22:33:44:55"}, exitUsage},
+		{[]string{"set", "--local", "fake0", "00:11:22:33:44:55"}, exitUsage},
+		{[]string{"show", "--dry-run", "fake0"}, exitUsage},
+		{[]string{"random", "--interval", "1m", "fake0"}, exitUsage},
+		{[]string{"rotate", "--dry-run", "fake0"}, exitUsage},
+		{[]string{"lookup", "--popular", "00:11:22:33:44:55"}, exitUsage},
+	} {
+		code, _, _ := runTest(h, c.args...)
+		assert.Equal(t, c.code, code, strings.Join(c.args, " "))
+	}
+	backend.SetNoNetAdmin(true)
+	code, _, stderr := runTest(h, "set", "fake0", "00:11:22:33:44:55")
+	assert.Equal(t, exitNotPermitted, code)
+	assert.NotEmpty(t, stderr)
+}
+
+func Test_Run_3(t *testing.T) {
+	h, _ := newTestHandle()
+	code, out, _ := runTest(h, "--json", "inventory")
+	assert.Equal(t, exitOK, code)
+	var entries []libmacouflage.InventoryEntry
+	assert.NoError(t, json.Unmarshal([]byte(out), &entries))
+	assert.Len(t, entries, 2)
+	assert.Equal(t, libmacouflage.KindEthernet, entries[0].Kind)
+	assert.True(t, entries[0].Allowed)
+	assert.False(t, entries[1].Allowed)
+	code, out, _ = runTest(h, "lookup", libmacouflage.OuiDb[0].VendorPrefix+":00:00:00")
+	assert.Equal(t, exitOK, code)
+	assert.Contains(t, out, libmacouflage.OuiDb[0].Vendor)
+}
+
+func Test_Run_4(t *testing.T) {
+	h, backend := newTestHandle()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+	var out bytes.Buffer
+	go func() {
+		done <- run(ctx, h, []string{"rotate", "--now", "--revert", "--mode", "random", "fake0"}, &out, &bytes.Buffer{})
+	}()
+	for len(backend.History("fake0")) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	assert.Equal(t, exitOK, <-done)
+	iface, _ := backend.Interface("fake0")
+	assert.Equal(t, iface.PermHardwareAddr, iface.HardwareAddr)
+	assert.Contains(t, out.String(), "fake0: ")
+}
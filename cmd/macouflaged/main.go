@@ -0,0 +1,107 @@
+// Command macouflaged serves the operations of libmacouflage to
+// unprivileged clients over a unix socket. It runs as root, or with
+// CAP_NET_ADMIN, and authorizes each call by the credentials of the caller.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/subgraph/libmacouflage"
+)
+
+func main() {
+	socket := flag.String("socket", "/run/macouflage.sock", "path of the unix socket")
+	allowUsers := flag.String("allow-user", "", "comma separated users allowed to change MACs, besides root")
+	allowGroups := flag.String("allow-group", "", "comma separated groups allowed to change MACs")
+	flag.Parse()
+	policy, err := buildPolicy(*allowUsers, *allowGroups)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "macouflaged: %v\n", err)
+		os.Exit(2)
+	}
+	// The socket is open to everyone, the policy decides who may do what
+	l, err := libmacouflage.ListenUnix(*socket, 0666)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "macouflaged: %v\n", err)
+		os.Exit(1)
+	}
+	defer os.Remove(*socket)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	s := libmacouflage.NewServer(libmacouflage.NewHandle(libmacouflage.GetLinkBackend()))
+	s.Policy = policy
+	err = s.Serve(ctx, l)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "macouflaged: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// buildPolicy extends the default policy with the users and groups given by
+// name or number
+func buildPolicy(users string, groups string) (policy libmacouflage.PeerPolicy, err error) {
+	var uids, gids []uint32
+	for _, name := range splitList(users) {
+		var id uint32
+		id, err = resolveID(name, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return
+		}
+		uids = append(uids, id)
+	}
+	for _, name := range splitList(groups) {
+		var id uint32
+		id, err = resolveID(name, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return
+		}
+		gids = append(gids, id)
+	}
+	policy = libmacouflage.AnyPeerPolicy(libmacouflage.DefaultPeerPolicy,
+		libmacouflage.AllowUIDs(uids...), libmacouflage.AllowGIDs(gids...))
+	return
+}
+
+func splitList(list string) (names []string) {
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+// resolveID returns the number name stands for, looking it up if it is not
+// a number already
+func resolveID(name string, lookup func(string) (string, error)) (id uint32, err error) {
+	text := name
+	if _, perr := strconv.ParseUint(name, 10, 32); perr != nil {
+		text, err = lookup(name)
+		if err != nil {
+			return
+		}
+	}
+	n, err := strconv.ParseUint(text, 10, 32)
+	id = uint32(n)
+	return
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/subgraph/libmacouflage"
+)
+
+func Test_BuildPolicy_1(t *testing.T) {
+	policy, err := buildPolicy("1000, root", "27")
+	assert.NoError(t, err)
+	assert.True(t, policy(libmacouflage.PeerCred{UID: 1000, GID: 1000}, libmacouflage.MethodSpoof))
+	assert.True(t, policy(libmacouflage.PeerCred{UID: 1001, GID: 1001, Groups: []uint32{27}}, libmacouflage.MethodSpoof))
+	assert.False(t, policy(libmacouflage.PeerCred{UID: 1001, GID: 1001}, libmacouflage.MethodSpoof))
+	assert.True(t, policy(libmacouflage.PeerCred{UID: 1001, GID: 1001}, libmacouflage.MethodLookup))
+	_, err = buildPolicy("no-such-user-anywhere", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,608 @@
+package libmacouflage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Methods of the daemon protocol
+const (
+	MethodInventory = "inventory"
+	MethodSpoof     = "spoof"
+	MethodRevert    = "revert"
+	MethodLookup    = "lookup"
+	MethodSubscribe = "subscribe"
+)
+
+// Name of the notifications pushed to subscribers
+const EventMacChanged = "mac_changed"
+
+// Largest request read, a longer one closes the connection
+const maxRequestSize = 64 << 10
+
+// A connection whose writes block this long is closed
+const sendTimeout = 5 * time.Second
+
+// Notifications queued for a subscriber, one that falls further behind is
+// dropped
+const subscriberQueue = 16
+
+// SO_PEERGROUPS of Linux 4.13, missing from syscall
+const SO_PEERGROUPS = 59
+
+// JSON-RPC 2.0 error codes, the last two are the daemon's own
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcFailed         = -32000
+	rpcDenied         = -32001
+)
+
+// PeerCred is the identity of the process at the other end of a unix
+// socket when it connected, from SO_PEERCRED. Groups are its supplementary
+// groups from SO_PEERGROUPS, nil when the kernel cannot tell them.
+type PeerCred struct {
+	PID    int32
+	UID    uint32
+	GID    uint32
+	Groups []uint32
+}
+
+// PeerPolicy decides whether the peer may call method.
+type PeerPolicy func(cred PeerCred, method string) bool
+
+// DefaultPeerPolicy lets root call every method and everyone else only
+// read.
+var DefaultPeerPolicy = AnyPeerPolicy(AllowUIDs(0), AllowMethods(MethodInventory, MethodLookup, MethodSubscribe))
+
+// AllowUIDs returns a policy that lets the given users call every method.
+func AllowUIDs(uids ...uint32) PeerPolicy {
+	return func(cred PeerCred, method string) bool {
+		for _, uid := range uids {
+			if cred.UID == uid {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AllowGIDs returns a policy that lets the members of the given groups call
+// every method, as primary or supplementary group. Peers whose groups are
+// unknown are denied.
+func AllowGIDs(gids ...uint32) PeerPolicy {
+	return func(cred PeerCred, method string) bool {
+		if cred.Groups == nil {
+			return false
+		}
+		for _, gid := range gids {
+			if cred.GID == gid {
+				return true
+			}
+			for _, group := range cred.Groups {
+				if group == gid {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// AllowMethods returns a policy that lets everyone call the given methods.
+func AllowMethods(methods ...string) PeerPolicy {
+	return func(cred PeerCred, method string) bool {
+		for _, m := range methods {
+			if m == method {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// AnyPeerPolicy returns a policy that allows what one of policies allows.
+func AnyPeerPolicy(policies ...PeerPolicy) PeerPolicy {
+	return func(cred PeerCred, method string) bool {
+		for _, policy := range policies {
+			if policy(cred, method) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Server serves the operations of a Handle as JSON-RPC 2.0 over unix socket
+// connections, one JSON value per request. Callers are authorized by their
+// peer credentials, subscribers get a mac_changed notification for each
+// change made through the server.
+type Server struct {
+	handle *Handle
+	// Policy is DefaultPeerPolicy unless set
+	Policy PeerPolicy
+	mu     sync.Mutex
+	conns  map[*serverConn]bool
+}
+
+type serverConn struct {
+	conn *net.UnixConn
+	cred PeerCred
+	// mu serializes the writes
+	mu      sync.Mutex
+	encoder *json.Encoder
+	// subscribed is guarded by the mutex of the server
+	subscribed bool
+	// events feeds the notifications to the subscriber, closed with the
+	// connection
+	events chan interface{}
+	done   chan struct{}
+}
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+}
+
+// rpcResponse has a result, null included, unless it has an error
+type rpcResponse struct {
+	JSONRPC string
+	Result  interface{}
+	Error   *rpcError
+	ID      json.RawMessage
+}
+
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type rpcResultJSON struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  interface{}     `json:"result"`
+	ID      json.RawMessage `json:"id"`
+}
+
+type rpcErrorJSON struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Error   *rpcError       `json:"error"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func (r rpcResponse) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(rpcErrorJSON{r.JSONRPC, r.Error, r.ID})
+	}
+	return json.Marshal(rpcResultJSON{r.JSONRPC, r.Result, r.ID})
+}
+
+// rpcError carries the sentinel the error matches, if any, as data
+type rpcError struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Data    *rpcErrorData `json:"data,omitempty"`
+}
+
+type rpcErrorData struct {
+	Kind string `json:"kind"`
+}
+
+type spoofParams struct {
+	Interface     string `json:"interface"`
+	Mode          string `json:"mode"`
+	Mac           string `json:"mac,omitempty"`
+	BIA           *bool  `json:"bia,omitempty"`
+	AutoDown      bool   `json:"auto_down,omitempty"`
+	DryRun        bool   `json:"dry_run,omitempty"`
+	DeviceType    string `json:"device_type,omitempty"`
+	VendorKeyword string `json:"vendor_keyword,omitempty"`
+}
+
+type interfaceParams struct {
+	Interface string `json:"interface"`
+	AutoDown  bool   `json:"auto_down,omitempty"`
+}
+
+type lookupParams struct {
+	Mac string `json:"mac"`
+}
+
+// MacChangedEvent is the parameter of mac_changed notifications.
+type MacChangedEvent struct {
+	Method string      `json:"method"`
+	UID    uint32      `json:"uid"`
+	Result SpoofResult `json:"result"`
+}
+
+// Names of the sentinels in error data, in the order they are tried
+var errorKinds = []struct {
+	err  error
+	kind string
+}{
+	{ErrNotPermitted, "not_permitted"},
+	{ErrNoSuchInterface, "no_such_interface"},
+	{ErrInvalidInterfaceType, "invalid_interface_type"},
+	{ErrInterfaceUp, "interface_up"},
+	{ErrAddressRejected, "address_rejected"},
+	{ErrInvalidMac, "invalid_mac"},
+	{ErrUnsupportedAddress, "unsupported_address"},
+	{ErrPermAddrUnsupported, "permanent_address_unavailable"},
+	{ErrNoVendor, "no_vendor"},
+	{ErrLinkNotReady, "link_not_ready"},
+	{ErrNotSupported, "not_supported"},
+	{ErrUnknownStrategy, "unknown_strategy"},
+	{ErrNoNetwork, "no_network"},
+	{ErrInvalidArgument, "invalid_argument"},
+	{ErrRandomSource, "random_source"},
+}
+
+func NewServer(h *Handle) *Server {
+	return &Server{handle: h, Policy: DefaultPeerPolicy, conns: make(map[*serverConn]bool)}
+}
+
+// ListenUnix listens on the unix socket at path with the given permissions,
+// replacing a socket nobody listens on anymore. The socket is bound in a
+// private directory and only moved to path once it has its permissions.
+// Closing l leaves the socket in place.
+func ListenUnix(path string, mode os.FileMode) (l *net.UnixListener, err error) {
+	if fi, serr := os.Lstat(path); serr == nil && fi.Mode()&os.ModeSocket != 0 {
+		conn, derr := net.Dial("unix", path)
+		if derr == nil {
+			conn.Close()
+			err = fmt.Errorf("Socket %s is in use", path)
+			return
+		}
+		os.Remove(path)
+	}
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".macouflage")
+	if err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	private := filepath.Join(dir, "sock")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: private, Net: "unix"})
+	if err != nil {
+		return
+	}
+	listener.SetUnlinkOnClose(false)
+	err = os.Chmod(private, mode)
+	if err == nil {
+		err = os.Rename(private, path)
+	}
+	if err != nil {
+		listener.Close()
+		return
+	}
+	l = listener
+	return
+}
+
+// Serve accepts connections on l until ctx is done, then closes l and the
+// connections.
+func (s *Server) Serve(ctx context.Context, l *net.UnixListener) (err error) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		l.Close()
+		s.mu.Lock()
+		for c := range s.conns {
+			c.conn.Close()
+		}
+		s.mu.Unlock()
+	}()
+	for {
+		conn, aerr := l.AcceptUnix()
+		if aerr != nil {
+			if ctx.Err() == nil {
+				err = aerr
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn *net.UnixConn) {
+	defer conn.Close()
+	cred, err := peerCred(conn)
+	if err != nil {
+		return
+	}
+	c := &serverConn{conn: conn, cred: cred, encoder: json.NewEncoder(conn), done: make(chan struct{})}
+	defer close(c.done)
+	s.mu.Lock()
+	if ctx.Err() != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.conns[c] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+	// The limit is reset for each request, the decoder reads ahead at most
+	// the rest of the previous one
+	limited := &io.LimitedReader{R: bufio.NewReader(conn)}
+	decoder := json.NewDecoder(limited)
+	for {
+		var req rpcRequest
+		limited.N = maxRequestSize
+		err = decoder.Decode(&req)
+		if err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			switch {
+			case limited.N <= 0:
+				c.send(rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcInvalidRequest, Message: "Request too large"},
+					ID: json.RawMessage("null")})
+			case errors.As(err, &syntaxErr):
+				c.send(rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcParseError, Message: err.Error()},
+					ID: json.RawMessage("null")})
+			case errors.As(err, &typeErr):
+				// Valid JSON that is no request object, batches included
+				c.send(rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcInvalidRequest, Message: "Invalid request"},
+					ID: json.RawMessage("null")})
+			}
+			return
+		}
+		result, rerr := s.call(ctx, c, req)
+		if req.ID == nil {
+			continue
+		}
+		response := rpcResponse{JSONRPC: "2.0", Result: result, Error: rerr, ID: req.ID}
+		if c.send(response) != nil {
+			return
+		}
+	}
+}
+
+// send writes v, a peer that does not read it in time is closed
+func (c *serverConn) send(v interface{}) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(sendTimeout))
+	err = c.encoder.Encode(v)
+	if err != nil {
+		c.conn.Close()
+	}
+	return
+}
+
+// notify writes the queued notifications until the connection is done
+func (c *serverConn) notify() {
+	for {
+		select {
+		case <-c.done:
+			return
+		case v := <-c.events:
+			if c.send(v) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) call(ctx context.Context, c *serverConn, req rpcRequest) (result interface{}, rerr *rpcError) {
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		rerr = &rpcError{Code: rpcInvalidRequest, Message: "Invalid request"}
+		return
+	}
+	switch req.Method {
+	case MethodInventory, MethodSpoof, MethodRevert, MethodLookup, MethodSubscribe:
+	default:
+		rerr = &rpcError{Code: rpcMethodNotFound, Message: "Method not found: " + req.Method}
+		return
+	}
+	policy := s.Policy
+	if policy == nil {
+		policy = DefaultPeerPolicy
+	}
+	if !policy(c.cred, req.Method) {
+		rerr = &rpcError{Code: rpcDenied, Message: fmt.Sprintf("uid %d may not call %s", c.cred.UID, req.Method),
+			Data: &rpcErrorData{"not_permitted"}}
+		return
+	}
+	var err error
+	switch req.Method {
+	case MethodInventory:
+		result, err = s.handle.Inventory()
+	case MethodSpoof:
+		var params spoofParams
+		if rerr = decodeParams(req.Params, &params); rerr != nil {
+			return
+		}
+		result, err = s.spoof(ctx, c, params)
+	case MethodRevert:
+		var params interfaceParams
+		if rerr = decodeParams(req.Params, &params); rerr != nil {
+			return
+		}
+		result, err = s.revert(ctx, c, params)
+	case MethodLookup:
+		var params lookupParams
+		if rerr = decodeParams(req.Params, &params); rerr != nil {
+			return
+		}
+		result, err = FindVendorByMac(params.Mac)
+	case MethodSubscribe:
+		s.mu.Lock()
+		if !c.subscribed {
+			c.subscribed = true
+			c.events = make(chan interface{}, subscriberQueue)
+			go c.notify()
+		}
+		s.mu.Unlock()
+		result = true
+	}
+	if err != nil {
+		result = nil
+		rerr = &rpcError{Code: rpcFailed, Message: err.Error()}
+		for _, k := range errorKinds {
+			if errors.Is(err, k.err) {
+				rerr.Data = &rpcErrorData{k.kind}
+				break
+			}
+		}
+	}
+	return
+}
+
+func decodeParams(raw json.RawMessage, params interface{}) *rpcError {
+	if raw == nil {
+		raw = json.RawMessage("{}")
+	}
+	if err := json.Unmarshal(raw, params); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) spoof(ctx context.Context, c *serverConn, params spoofParams) (result SpoofResult, err error) {
+	var strategy Strategy
+	if params.Mode == ModeSpecific || (params.Mode == "" && params.Mac != "") {
+		var mac net.HardwareAddr
+		mac, err = net.ParseMAC(params.Mac)
+		if err != nil {
+			err = InvalidMacError{params.Mac, err}
+			return
+		}
+		strategy = SpecificStrategy(mac)
+	} else {
+		strategy, err = LookupStrategy(params.Mode)
+		if err != nil {
+			return
+		}
+	}
+	var opts []SpoofOption
+	if params.BIA != nil {
+		opts = append(opts, WithBIA(*params.BIA))
+	}
+	if params.AutoDown {
+		opts = append(opts, WithAutoDown())
+	}
+	if params.DryRun {
+		opts = append(opts, WithDryRun())
+	}
+	if params.DeviceType != "" {
+		opts = append(opts, WithDeviceType(params.DeviceType))
+	}
+	if params.VendorKeyword != "" {
+		opts = append(opts, WithVendorKeyword(params.VendorKeyword))
+	}
+	result, err = s.handle.Spoof(ctx, params.Interface, strategy, opts...)
+	if err == nil && !result.DryRun {
+		s.broadcast(MacChangedEvent{MethodSpoof, c.cred.UID, result})
+	}
+	return
+}
+
+func (s *Server) revert(ctx context.Context, c *serverConn, params interfaceParams) (result SpoofResult, err error) {
+	perm, err := s.handle.GetPermanentMac(params.Interface)
+	if err != nil {
+		return
+	}
+	var opts []SpoofOption
+	if params.AutoDown {
+		opts = append(opts, WithAutoDown())
+	}
+	result, err = s.handle.Spoof(ctx, params.Interface, SpecificStrategy(perm), opts...)
+	if err == nil {
+		s.broadcast(MacChangedEvent{MethodRevert, c.cred.UID, result})
+	}
+	return
+}
+
+// broadcast queues the notification for the subscribers without waiting
+// for them, those whose queue is full are closed
+func (s *Server) broadcast(event MacChangedEvent) {
+	notification := rpcNotification{"2.0", EventMacChanged, event}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		if !c.subscribed {
+			continue
+		}
+		select {
+		case c.events <- notification:
+		default:
+			c.conn.Close()
+		}
+	}
+}
+
+// peerCred reads the credentials of the peer of conn
+func peerCred(conn *net.UnixConn) (cred PeerCred, err error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return
+	}
+	var ucred *syscall.Ucred
+	var uerr error
+	err = raw.Control(func(fd uintptr) {
+		ucred, uerr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err == nil {
+		err = uerr
+	}
+	if err != nil {
+		return
+	}
+	cred = PeerCred{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}
+	err = raw.Control(func(fd uintptr) {
+		cred.Groups, uerr = getsockoptGroups(int(fd))
+	})
+	if err == nil && uerr != nil {
+		cred.Groups = nil
+	}
+	err = nil
+	return
+}
+
+// getsockoptGroups reads the supplementary groups the peer had when it
+// connected, which the kernel keeps with the socket
+func getsockoptGroups(fd int) (groups []uint32, err error) {
+	buf := make([]uint32, 32)
+	for {
+		size := uint32(len(buf) * 4)
+		var p unsafe.Pointer
+		if len(buf) > 0 {
+			p = unsafe.Pointer(&buf[0])
+		}
+		err = getsockopt(fd, syscall.SOL_SOCKET, SO_PEERGROUPS, p, &size)
+		if errors.Is(err, syscall.ERANGE) && int(size/4) > len(buf) {
+			buf = make([]uint32, size/4)
+			continue
+		}
+		if err != nil {
+			return
+		}
+		groups = append([]uint32{}, buf[:size/4]...)
+		return
+	}
+}
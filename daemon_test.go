@@ -0,0 +1,192 @@
+package libmacouflage
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testRPCClient struct {
+	conn    net.Conn
+	encoder *json.Encoder
+	decoder *json.Decoder
+}
+
+type testRPCMessage struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+	ID     json.RawMessage `json:"id"`
+}
+
+// startTestServer serves the fake backend on a socket of a temporary
+// directory until the test ends
+func startTestServer(t *testing.T, backend *FakeBackend, policy PeerPolicy) string {
+	path := filepath.Join(t.TempDir(), "macouflage.sock")
+	l, err := ListenUnix(path, 0600)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	s := NewServer(NewHandle(backend))
+	s.Policy = policy
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Serve(ctx, l)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-done)
+	})
+	return path
+}
+
+func dialTestServer(t *testing.T, path string) *testRPCClient {
+	conn, err := net.Dial("unix", path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testRPCClient{conn, json.NewEncoder(conn), json.NewDecoder(conn)}
+}
+
+func (c *testRPCClient) call(method string, params interface{}) (msg testRPCMessage) {
+	c.encoder.Encode(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params, "id": 1})
+	c.decoder.Decode(&msg)
+	return
+}
+
+func Test_PeerPolicy_1(t *testing.T) {
+	user := PeerCred{UID: 1000, GID: 1000, Groups: []uint32{1000, 27}}
+	assert.True(t, DefaultPeerPolicy(PeerCred{}, MethodSpoof))
+	assert.False(t, DefaultPeerPolicy(user, MethodSpoof))
+	assert.True(t, DefaultPeerPolicy(user, MethodInventory))
+	assert.True(t, AllowGIDs(27)(user, MethodRevert))
+	assert.False(t, AllowGIDs(4)(user, MethodRevert))
+	assert.True(t, AnyPeerPolicy(AllowUIDs(1), AllowUIDs(1000))(user, MethodSpoof))
+	assert.False(t, AllowGIDs(1000)(PeerCred{UID: 1000, GID: 1000}, MethodRevert))
+}
+
+func Test_Server_1(t *testing.T) {
+	backend := newTestFakeBackend()
+	path := startTestServer(t, backend, AllowUIDs(uint32(os.Getuid())))
+	subscriber := dialTestServer(t, path)
+	msg := subscriber.call(MethodSubscribe, nil)
+	assert.Nil(t, msg.Error)
+	assert.Equal(t, "true", string(msg.Result))
+	client := dialTestServer(t, path)
+	msg = client.call(MethodSpoof, map[string]interface{}{"interface": "fake0", "mode": ModeRandom})
+	assert.Nil(t, msg.Error)
+	var result SpoofResult
+	assert.NoError(t, json.Unmarshal(msg.Result, &result))
+	iface, _ := backend.Interface("fake0")
+	assert.Equal(t, iface.HardwareAddr, result.NewMac)
+	var event testRPCMessage
+	assert.NoError(t, subscriber.decoder.Decode(&event))
+	assert.Equal(t, EventMacChanged, event.Method)
+	var changed MacChangedEvent
+	assert.NoError(t, json.Unmarshal(event.Params, &changed))
+	assert.Equal(t, MethodSpoof, changed.Method)
+	assert.Equal(t, uint32(os.Getuid()), changed.UID)
+	assert.Equal(t, result.NewMac, changed.Result.NewMac)
+	msg = client.call(MethodRevert, map[string]interface{}{"interface": "fake0"})
+	assert.Nil(t, msg.Error)
+	iface, _ = backend.Interface("fake0")
+	assert.Equal(t, iface.PermHardwareAddr, iface.HardwareAddr)
+	var entries []InventoryEntry
+	msg = client.call(MethodInventory, nil)
+	assert.NoError(t, json.Unmarshal(msg.Result, &entries))
+	assert.Len(t, entries, 2)
+}
+
+func Test_Server_2(t *testing.T) {
+	path := startTestServer(t, newTestFakeBackend(), AllowMethods(MethodLookup, MethodInventory))
+	client := dialTestServer(t, path)
+	msg := client.call(MethodSpoof, map[string]interface{}{"interface": "fake0", "mode": ModeRandom})
+	assert.Equal(t, rpcDenied, msg.Error.Code)
+	msg = client.call("reboot", nil)
+	assert.Equal(t, rpcMethodNotFound, msg.Error.Code)
+	msg = client.call(MethodLookup, map[string]interface{}{"mac": "ff:ff:fe:00:00:00"})
+	assert.Equal(t, rpcFailed, msg.Error.Code)
+	assert.Equal(t, "no_vendor", msg.Error.Data.Kind)
+	msg = client.call(MethodLookup, map[string]interface{}{"mac": OuiDb[0].VendorPrefix + ":00:00:00"})
+	var oui Oui
+	assert.NoError(t, json.Unmarshal(msg.Result, &oui))
+	assert.Equal(t, OuiDb[0].Vendor, oui.Vendor)
+	client.conn.Write([]byte("{nonsense\n"))
+	var parseErr testRPCMessage
+	assert.NoError(t, client.decoder.Decode(&parseErr))
+	assert.Equal(t, rpcParseError, parseErr.Error.Code)
+}
+
+func Test_PeerCred_1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cred.sock")
+	l, err := ListenUnix(path, 0600)
+	assert.NoError(t, err)
+	defer l.Close()
+	client, err := net.Dial("unix", path)
+	assert.NoError(t, err)
+	defer client.Close()
+	conn, err := l.AcceptUnix()
+	assert.NoError(t, err)
+	defer conn.Close()
+	cred, err := peerCred(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(os.Getuid()), cred.UID)
+	assert.Equal(t, uint32(os.Getgid()), cred.GID)
+	assert.Equal(t, int32(os.Getpid()), cred.PID)
+	groups, _ := os.Getgroups()
+	assert.NotNil(t, cred.Groups)
+	assert.Len(t, cred.Groups, len(groups))
+	_, err = ListenUnix(path, 0600)
+	assert.Error(t, err)
+	fi, err := os.Lstat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, entries, 1)
+}
+
+func Test_Server_3(t *testing.T) {
+	path := startTestServer(t, NewFakeBackend(), AllowMethods(MethodInventory, MethodSpoof))
+	client := dialTestServer(t, path)
+	var raw map[string]json.RawMessage
+	client.encoder.Encode(map[string]interface{}{"jsonrpc": "2.0", "method": MethodInventory, "id": 1})
+	assert.NoError(t, client.decoder.Decode(&raw))
+	assert.Equal(t, "null", string(raw["result"]))
+	assert.NotContains(t, raw, "error")
+	msg := client.call(MethodSpoof, map[string]interface{}{"interface": strings.Repeat("x", maxRequestSize)})
+	if assert.NotNil(t, msg.Error) {
+		assert.Equal(t, rpcInvalidRequest, msg.Error.Code)
+	}
+	for _, request := range []string{`[{"jsonrpc": "2.0", "method": "inventory", "id": 1}]`, `42`} {
+		client = dialTestServer(t, path)
+		client.conn.Write([]byte(request + "\n"))
+		msg = testRPCMessage{}
+		assert.NoError(t, client.decoder.Decode(&msg), request)
+		assert.Equal(t, "null", string(msg.ID), request)
+		if assert.NotNil(t, msg.Error, request) {
+			assert.Equal(t, rpcInvalidRequest, msg.Error.Code, request)
+		}
+	}
+}
+
+func Test_Server_4(t *testing.T) {
+	backend := newTestFakeBackend()
+	path := startTestServer(t, backend, AllowUIDs(uint32(os.Getuid())))
+	subscriber := dialTestServer(t, path)
+	subscriber.call(MethodSubscribe, nil)
+	client := dialTestServer(t, path)
+	// The subscriber reads nothing, the changes must go through regardless
+	for i := 0; i < 2*subscriberQueue; i++ {
+		msg := client.call(MethodSpoof, map[string]interface{}{"interface": "fake0", "mode": ModeRandom})
+		assert.Nil(t, msg.Error)
+	}
+}
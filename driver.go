@@ -0,0 +1,144 @@
+package libmacouflage
+
+import (
+	"bytes"
+	"syscall"
+	"unsafe"
+)
+
+const ETHTOOL_GDRVINFO = 0x00000003
+
+// LiveChangeSupport tells whether a driver accepts a new MAC while the link
+// is up, which drivers flag with IFF_LIVE_ADDR_CHANGE in the kernel.
+type LiveChangeSupport int
+
+const (
+	LiveChangeUnknown LiveChangeSupport = iota
+	LiveChangeSupported
+	LiveChangeUnsupported
+)
+
+// DriverCapabilities describes what the driver of an interface allows.
+type DriverCapabilities struct {
+	Driver         string
+	LiveAddrChange LiveChangeSupport
+}
+
+// DriverBackend is implemented by backends that can name the driver
+// behind a link.
+type DriverBackend interface {
+	LinkDriver(link Link) (string, error)
+}
+
+// EthtoolDrvinfo is struct ethtool_drvinfo
+type EthtoolDrvinfo struct {
+	cmd         uint32
+	driver      [32]byte
+	version     [32]byte
+	fwVersion   [32]byte
+	busInfo     [32]byte
+	eromVersion [32]byte
+	reserved2   [12]byte
+	nPrivFlags  uint32
+	nStats      uint32
+	testinfoLen uint32
+	eedumpLen   uint32
+	regdumpLen  uint32
+}
+
+// Drivers known to accept an address change on a running link. Drivers
+// missing here are still tried, the kernel refuses with EBUSY if needed.
+var liveChangeDrivers = map[string]LiveChangeSupport{
+	"bridge":     LiveChangeSupported,
+	"dummy":      LiveChangeSupported,
+	"e1000":      LiveChangeSupported,
+	"e1000e":     LiveChangeSupported,
+	"geneve":     LiveChangeSupported,
+	"hv_netvsc":  LiveChangeSupported,
+	"igb":        LiveChangeSupported,
+	"ixgbe":      LiveChangeSupported,
+	"macvlan":    LiveChangeSupported,
+	"mlx5_core":  LiveChangeSupported,
+	"r8169":      LiveChangeSupported,
+	"team":       LiveChangeSupported,
+	"tun":        LiveChangeSupported,
+	"veth":       LiveChangeSupported,
+	"virtio_net": LiveChangeSupported,
+	"vmxnet3":    LiveChangeSupported,
+	"vxlan":      LiveChangeSupported,
+	"ath9k":      LiveChangeUnsupported,
+	"ath10k_pci": LiveChangeUnsupported,
+	"brcmfmac":   LiveChangeUnsupported,
+	"iwlwifi":    LiveChangeUnsupported,
+	"mt7921e":    LiveChangeUnsupported,
+	"rtl8xxxu":   LiveChangeUnsupported,
+}
+
+func (s LiveChangeSupport) String() string {
+	switch s {
+	case LiveChangeSupported:
+		return "supported"
+	case LiveChangeUnsupported:
+		return "unsupported"
+	}
+	return "unknown"
+}
+
+func ProbeDriverCapabilities(name string) (caps DriverCapabilities, err error) {
+	return defaultHandle().ProbeDriverCapabilities(name)
+}
+
+func (h *Handle) ProbeDriverCapabilities(name string) (caps DriverCapabilities, err error) {
+	link, err := h.lookup(name)
+	if err != nil {
+		return
+	}
+	caps = h.driverCapabilities(link)
+	return
+}
+
+// driverCapabilities never fails, a driver it cannot name is unknown
+func (h *Handle) driverCapabilities(link Link) (caps DriverCapabilities) {
+	driverBackend, ok := h.backend.(DriverBackend)
+	if !ok {
+		return
+	}
+	driver, err := driverBackend.LinkDriver(link)
+	if err != nil {
+		return
+	}
+	caps.Driver = driver
+	caps.LiveAddrChange = liveChangeDrivers[driver]
+	return
+}
+
+func (b NetlinkBackend) LinkDriver(link Link) (string, error) {
+	return getEthtoolDriver(b.NetNS, link.Name)
+}
+
+func (b IoctlBackend) LinkDriver(link Link) (string, error) {
+	return getEthtoolDriver(b.NetNS, link.Name)
+}
+
+func getEthtoolDriver(ns NetNS, name string) (driver string, err error) {
+	sockfd, err := openIoctlSocket(ns)
+	if err != nil {
+		return
+	}
+	defer syscall.Close(sockfd)
+	var drvinfo EthtoolDrvinfo
+	drvinfo.cmd = ETHTOOL_GDRVINFO
+	var ifr ifreqData
+	copy(ifr.name[:IFNAMSIZ-1], []byte(name))
+	ifr.data = unsafe.Pointer(&drvinfo)
+	err = ioctl(sockfd, SIOCETHTOOL, unsafe.Pointer(&ifr))
+	if err != nil {
+		return
+	}
+	if end := bytes.IndexByte(drvinfo.driver[:], 0); end >= 0 {
+		driver = string(drvinfo.driver[:end])
+	} else {
+		driver = string(drvinfo.driver[:])
+	}
+	return
+}
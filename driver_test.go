@@ -0,0 +1,26 @@
+package libmacouflage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ProbeDriverCapabilities_1(t *testing.T) {
+	_, err := ProbeDriverCapabilities("badinterface")
+	assert.Error(t, err, "Function failed to generate error for bad interface")
+}
+
+func Test_ProbeDriverCapabilities_2(t *testing.T) {
+	iface := GetTestInterface()
+	caps, err := ProbeDriverCapabilities(iface)
+	assert.NoError(t, err, iface)
+	assert.NotEqual(t, "", caps.Driver, iface)
+}
+
+func Test_ProbeDriverCapabilities_3(t *testing.T) {
+	h := NewHandle(newRecordingBackend("rec0"))
+	caps, err := h.ProbeDriverCapabilities("rec0")
+	assert.NoError(t, err)
+	assert.Equal(t, LiveChangeUnknown, caps.LiveAddrChange)
+}
@@ -0,0 +1,143 @@
+package libmacouflage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Longest run of epochs ResolveEpochMac searches
+const maxEpochSearch = 1 << 20
+
+// Epoch cuts time into periods of Length from Origin, the Unix epoch when
+// zero.
+type Epoch struct {
+	Origin time.Time
+	Length time.Duration
+}
+
+// Epochs starting at midnight UTC
+var (
+	Daily = Epoch{Length: 24 * time.Hour}
+	// Weekly epochs start on Monday, the Unix epoch was a Thursday
+	Weekly = Epoch{Origin: time.Date(1970, 1, 5, 0, 0, 0, 0, time.UTC), Length: 7 * 24 * time.Hour}
+)
+
+// EpochHost is an interface whose epoch addresses can be recognized: its
+// permanent address and the secret they are derived with. Name is for the
+// caller.
+type EpochHost struct {
+	Name      string
+	Secret    []byte
+	Permanent net.HardwareAddr
+}
+
+// EpochMatch is a host and epoch an address was derived for.
+type EpochMatch struct {
+	Host  EpochHost
+	Index int64
+	Start time.Time
+}
+
+func (e Epoch) origin() time.Time {
+	if e.Origin.IsZero() {
+		return time.Unix(0, 0).UTC()
+	}
+	return e.Origin
+}
+
+// Index returns the number of the epoch t falls in, negative before Origin.
+func (e Epoch) Index(t time.Time) int64 {
+	d := t.Sub(e.origin())
+	index := int64(d / e.Length)
+	if d < 0 && d%e.Length != 0 {
+		index--
+	}
+	return index
+}
+
+// Start returns the time the epoch number index begins.
+func (e Epoch) Start(index int64) time.Time {
+	return e.origin().Add(time.Duration(index) * e.Length)
+}
+
+// Next returns the time the epoch after the one of t begins, when an epoch
+// address is due for a change.
+func (e Epoch) Next(t time.Time) time.Time {
+	return e.Start(e.Index(t) + 1)
+}
+
+// EpochStrategy derives the address from secret, the permanent address and
+// the epoch the time of clock is in, SystemClock when nil. The address
+// changes with each epoch, and whoever holds the secret can tell the host and
+// epoch back from it with ResolveEpochMac.
+func EpochStrategy(secret []byte, epoch Epoch, clock Clock, prefix PrefixSource) Strategy {
+	secret = append([]byte(nil), secret...)
+	if clock == nil {
+		clock = SystemClock
+	}
+	return NewMode("Epoch", "Set a MAC derived from the current epoch", "", ModeEpoch,
+		func(current net.HardwareAddr, permanent net.HardwareAddr) (mac net.HardwareAddr, err error) {
+			if epoch.Length <= 0 {
+				err = fmt.Errorf("Epochs need a positive length")
+				return
+			}
+			return deriveMac(secret, epochLabel, current, permanent, epochData(epoch.Index(clock.Now())), prefix)
+		})
+}
+
+// EpochMac returns the address EpochStrategy derives for the interface with
+// the permanent address in the epoch number index.
+func EpochMac(secret []byte, permanent net.HardwareAddr, index int64, prefix PrefixSource) (mac net.HardwareAddr, err error) {
+	return deriveMac(secret, epochLabel, permanent, permanent, epochData(index), prefix)
+}
+
+// ResolveEpochMac finds the hosts and epochs between from and to that mac was
+// derived for with prefix. Several matches are possible, most likely with a
+// vendor prefix, which leaves fewer bits to the digest. Addresses whose
+// burned-in bit was changed after derivation, as WithBIA does, do not match.
+func ResolveEpochMac(mac net.HardwareAddr, hosts []EpochHost, epoch Epoch, from time.Time, to time.Time, prefix PrefixSource) (matches []EpochMatch, err error) {
+	if epoch.Length <= 0 {
+		err = fmt.Errorf("Epochs need a positive length")
+		return
+	}
+	first, last := epoch.Index(from), epoch.Index(to)
+	if last < first || last-first >= maxEpochSearch {
+		err = fmt.Errorf("Cannot search %s to %s, at most %d epochs can be", from, to, maxEpochSearch)
+		return
+	}
+	format, err := formatOf(mac)
+	if err != nil {
+		return
+	}
+	target := format.EUI(mac)
+	if target == nil {
+		msg := fmt.Sprintf("No derived part in the hardware address of %s interfaces", format.Name)
+		err = UnsupportedAddressError{msg}
+		return
+	}
+	for _, host := range hosts {
+		if len(host.Permanent) != len(mac) {
+			continue
+		}
+		for index := first; index <= last; index++ {
+			derived, derr := EpochMac(host.Secret, host.Permanent, index, prefix)
+			if derr != nil {
+				err = derr
+				return
+			}
+			if bytes.Equal(format.EUI(derived), target) {
+				matches = append(matches, EpochMatch{host, index, epoch.Start(index)})
+			}
+		}
+	}
+	return
+}
+
+func epochData(index int64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, uint64(index))
+	return data
+}
@@ -0,0 +1,60 @@
+package libmacouflage
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Epoch_1(t *testing.T) {
+	monday := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	index := Weekly.Index(monday)
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Weekly.Start(index))
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), Weekly.Next(monday))
+	assert.Equal(t, int64(-1), Daily.Index(time.Unix(-1, 0)))
+	assert.Equal(t, int64(0), Daily.Index(time.Unix(0, 0)))
+}
+
+func Test_EpochStrategy_1(t *testing.T) {
+	backend := newTestFakeBackend()
+	h := NewHandle(backend)
+	clock := NewFakeClock(time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC))
+	secret := []byte("fleet secret")
+	strategy := EpochStrategy(secret, Daily, clock, PrefixKeep)
+	first, err := h.Spoof(context.Background(), "fake0", strategy)
+	assert.NoError(t, err)
+	assert.Equal(t, first.PermanentMac[:3], first.NewMac[:3])
+	clock.Advance(time.Hour)
+	again, _ := strategy.Generate(first.NewMac, first.PermanentMac)
+	assert.Equal(t, first.NewMac, again)
+	clock.Advance(24 * time.Hour)
+	second, err := h.Spoof(context.Background(), "fake0", strategy)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.NewMac, second.NewMac)
+
+	other, _ := net.ParseMAC("00:11:22:33:44:55")
+	hosts := []EpochHost{
+		{"other", secret, other},
+		{"fake0", []byte("wrong secret"), first.PermanentMac},
+		{"fake0", secret, first.PermanentMac},
+	}
+	from, to := clock.Now().Add(-7*24*time.Hour), clock.Now()
+	matches, err := ResolveEpochMac(first.NewMac, hosts, Daily, from, to, PrefixKeep)
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, secret, matches[0].Host.Secret)
+		assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), matches[0].Start)
+	}
+	matches, err = ResolveEpochMac(second.NewMac, hosts, Daily, from, to, PrefixKeep)
+	assert.NoError(t, err)
+	if assert.Len(t, matches, 1) {
+		assert.Equal(t, Daily.Index(clock.Now()), matches[0].Index)
+	}
+	matches, _ = ResolveEpochMac(first.NewMac, hosts, Daily, from, to, PrefixLocal)
+	assert.Empty(t, matches)
+	_, err = ResolveEpochMac(first.NewMac, hosts, Daily, to, from, PrefixKeep)
+	assert.Error(t, err)
+}
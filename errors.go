@@ -0,0 +1,177 @@
+package libmacouflage
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// Errors the exported functions can be tested against with errors.Is. The
+// errors returned are structured and wrap the underlying cause, such as a
+// syscall.Errno, which errors.Is and errors.As also find.
+var (
+	ErrNotPermitted         = errors.New("operation not permitted")
+	ErrNoSuchInterface      = errors.New("no such interface")
+	ErrInvalidInterfaceType = errors.New("invalid interface type")
+	ErrInterfaceUp          = errors.New("interface is up")
+	ErrAddressRejected      = errors.New("address rejected")
+	ErrInvalidMac           = errors.New("invalid MAC address")
+	ErrUnsupportedAddress   = errors.New("unsupported hardware address")
+	ErrPermAddrUnsupported  = errors.New("permanent address unavailable")
+	ErrNoVendor             = errors.New("no vendor found")
+	ErrLinkNotReady         = errors.New("link not ready")
+	ErrNotSupported         = errors.New("not supported by the link backend")
+	ErrUnknownStrategy      = errors.New("unknown strategy")
+	ErrStrategyExists       = errors.New("strategy already registered")
+	ErrNoNetwork            = errors.New("no network found")
+	ErrInvalidArgument      = errors.New("invalid argument")
+	ErrRandomSource         = errors.New("random source failed")
+)
+
+// LinkError records the operation on an interface that failed. Kind is one
+// of the Err sentinels, nil when the cause fits none of them.
+type LinkError struct {
+	Kind      error
+	Op        string
+	Interface string
+	Err       error
+}
+
+// AddressRejectedError is returned when the kernel or the driver refuses
+// to set Mac on the interface.
+type AddressRejectedError struct {
+	Interface string
+	Mac       net.HardwareAddr
+	Err       error
+}
+
+type InvalidMacError struct {
+	Mac string
+	Err error
+}
+
+func (e LinkError) Error() string {
+	msg := e.Op
+	if e.Interface != "" {
+		msg += " " + e.Interface
+	}
+	if e.Kind != nil {
+		msg += ": " + e.Kind.Error()
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e LinkError) Unwrap() error {
+	return e.Err
+}
+
+func (e LinkError) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+func (e AddressRejectedError) Error() string {
+	return fmt.Sprintf("%s refused MAC %s: %v", e.Interface, e.Mac, e.Err)
+}
+
+func (e AddressRejectedError) Unwrap() error {
+	return e.Err
+}
+
+func (e AddressRejectedError) Is(target error) bool {
+	return target == ErrAddressRejected
+}
+
+func (e InvalidMacError) Error() string {
+	return fmt.Sprintf("Invalid MAC address %q: %v", e.Mac, e.Err)
+}
+
+func (e InvalidMacError) Unwrap() error {
+	return e.Err
+}
+
+func (e InvalidMacError) Is(target error) bool {
+	return target == ErrInvalidMac
+}
+
+func (e InvalidInterfaceTypeError) Is(target error) bool {
+	return target == ErrInvalidInterfaceType
+}
+
+func (e NoVendorError) Is(target error) bool {
+	return target == ErrNoVendor
+}
+
+func (e UnsupportedAddressError) Is(target error) bool {
+	return target == ErrUnsupportedAddress
+}
+
+func (e MissingCapabilityError) Is(target error) bool {
+	return target == ErrNotPermitted
+}
+
+// linkError wraps err from op on the interface called name, errors that
+// already are structured are returned as they are
+func linkError(op string, name string, err error) error {
+	if err == nil || isStructuredError(err) {
+		return err
+	}
+	return LinkError{errnoKind(err), op, name, err}
+}
+
+// setAddressError wraps the error of setting mac. Only the errnos the kernel
+// and the drivers refuse an address with are a rejection, any other error,
+// such as a failed netlink exchange, is a LinkError.
+func setAddressError(name string, mac net.HardwareAddr, err error) error {
+	if err == nil || isStructuredError(err) {
+		return err
+	}
+	if isAddressRefusal(err) {
+		return AddressRejectedError{name, append(net.HardwareAddr(nil), mac...), err}
+	}
+	return LinkError{errnoKind(err), "set", name, err}
+}
+
+// isAddressRefusal reports whether err is an errno refusing the address
+// itself
+func isAddressRefusal(err error) bool {
+	var errno syscall.Errno
+	if !errors.As(err, &errno) {
+		return false
+	}
+	switch errno {
+	case syscall.EADDRNOTAVAIL, syscall.EINVAL, syscall.EOPNOTSUPP, syscall.ERANGE:
+		return true
+	}
+	return false
+}
+
+func errnoKind(err error) error {
+	switch {
+	case errors.Is(err, syscall.EPERM), errors.Is(err, syscall.EACCES):
+		return ErrNotPermitted
+	case errors.Is(err, syscall.ENODEV), errors.Is(err, syscall.ENXIO), isNoSuchInterface(err):
+		return ErrNoSuchInterface
+	case errors.Is(err, syscall.EBUSY):
+		return ErrInterfaceUp
+	}
+	return nil
+}
+
+// The net package reports a missing interface with an error of its own
+func isNoSuchInterface(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Err != nil && opErr.Err.Error() == "no such network interface"
+}
+
+func isStructuredError(err error) bool {
+	switch err.(type) {
+	case LinkError, AddressRejectedError, InvalidMacError, InvalidInterfaceTypeError,
+		NoVendorError, UnsupportedAddressError, MissingCapabilityError:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package libmacouflage
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Errors_1(t *testing.T) {
+	backend := newTestFakeBackend()
+	h := NewHandle(backend)
+	_, err := h.GetCurrentMac("missing0")
+	assert.True(t, errors.Is(err, ErrNoSuchInterface))
+	assert.True(t, errors.Is(err, syscall.ENODEV))
+	var linkErr LinkError
+	assert.True(t, errors.As(err, &linkErr))
+	assert.Equal(t, "missing0", linkErr.Interface)
+	_, err = h.GetCurrentMac("lo")
+	assert.True(t, errors.Is(err, ErrInvalidInterfaceType))
+	err = h.SetMac("fake0", "00:11:22:33:44")
+	assert.True(t, errors.Is(err, ErrInvalidMac))
+	backend.SetNoNetAdmin(true)
+	err = h.SetMac("fake0", "00:11:22:00:00:01")
+	assert.True(t, errors.Is(err, ErrNotPermitted))
+}
+
+func Test_Errors_2(t *testing.T) {
+	backend := newTestFakeBackend()
+	backend.AddDriverRule("e1000e", RejectPrefix(net.HardwareAddr{0x00, 0x11, 0x22}))
+	h := NewHandle(backend)
+	err := h.SetMac("fake0", "00:11:22:00:00:01")
+	assert.True(t, errors.Is(err, ErrAddressRejected))
+	assert.True(t, errors.Is(err, syscall.EADDRNOTAVAIL))
+	var rejected AddressRejectedError
+	assert.True(t, errors.As(err, &rejected))
+	assert.Equal(t, "00:11:22:00:00:01", rejected.Mac.String())
+	backend.InjectError(FakeOpSet, "fake0", syscall.EPERM)
+	err = h.SetMac("fake0", "00:11:22:00:00:01")
+	assert.True(t, errors.Is(err, ErrNotPermitted))
+	assert.False(t, errors.Is(err, ErrAddressRejected))
+	for _, cause := range []error{syscall.ENOBUFS, errors.New("netlink message truncated")} {
+		backend.ClearErrors()
+		backend.InjectError(FakeOpSet, "fake0", cause)
+		result, err := h.Spoof(context.Background(), "fake0", RandomStrategy(true))
+		assert.False(t, errors.Is(err, ErrAddressRejected), cause.Error())
+		assert.True(t, errors.As(err, &LinkError{}), cause.Error())
+		assert.True(t, errors.Is(err, cause))
+		assert.Equal(t, 1, result.Attempts)
+	}
+}
+
+func Test_Errors_3(t *testing.T) {
+	backend := newTestFakeBackend()
+	backend.AddDriverRule("e1000e", RejectWhileUp)
+	backend.AddInterface(FakeInterface{Name: "fake0", Driver: "e1000e", Up: true,
+		HardwareAddr: net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55}})
+	h := NewHandle(backend)
+	_, err := h.GetPermanentMac("fake0")
+	assert.True(t, errors.Is(err, ErrPermAddrUnsupported))
+	err = h.SetMac("fake0", "00:11:22:00:00:01")
+	assert.True(t, errors.Is(err, ErrInterfaceUp))
+	assert.True(t, errors.Is(err, syscall.EBUSY))
+	_, err = FindVendorByMac("ff:ff:fe:00:00:00")
+	assert.True(t, errors.Is(err, ErrNoVendor))
+}
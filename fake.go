@@ -0,0 +1,418 @@
+package libmacouflage
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+)
+
+// FakeOp names the FakeBackend operations errors can be injected into.
+type FakeOp string
+
+const (
+	FakeOpLinks    FakeOp = "links"
+	FakeOpLookup   FakeOp = "lookup"
+	FakeOpSet      FakeOp = "set"
+	FakeOpUp       FakeOp = "up"
+	FakeOpDown     FakeOp = "down"
+	FakeOpPermAddr FakeOp = "permaddr"
+	FakeOpNetwork  FakeOp = "network"
+)
+
+// FakeInterface is a simulated interface of a FakeBackend. A nil
+// PermHardwareAddr simulates an interface whose permanent address neither
+// the kernel nor the driver can report.
+type FakeInterface struct {
+	Index            int
+	Name             string
+	Kind             InterfaceKind
+	HardwareType     uint16
+	HardwareAddr     net.HardwareAddr
+	PermHardwareAddr net.HardwareAddr
+	MTU              int
+	Up               bool
+	NoCarrier        bool
+	Driver           string
+	// Network is the network the interface attaches to, none without a
+	// gateway MAC
+	Network Network
+	// Rules apply to this interface on top of the rules of its driver
+	Rules []FakeRule
+}
+
+// FakeRule lets a simulated driver refuse an address change by returning
+// an error, as the kernel would.
+type FakeRule func(iface FakeInterface, mac net.HardwareAddr) error
+
+// FakeBackend is an in-memory LinkBackend that needs no privileges. Every
+// Handle built on it acts as if it held CAP_NET_ADMIN unless SetNoNetAdmin
+// takes it away.
+type FakeBackend struct {
+	mu          sync.Mutex
+	id          string
+	ifaces      []*FakeInterface
+	driverRules map[string][]FakeRule
+	errors      map[fakeErrorKey]error
+	history     map[string][]net.HardwareAddr
+	noNetAdmin  bool
+}
+
+type fakeErrorKey struct {
+	op   FakeOp
+	name string
+}
+
+var fakeBackendCount = struct {
+	sync.Mutex
+	n int
+}{}
+
+// RejectWhileUp refuses changes on an up interface with EBUSY, like the
+// drivers without IFF_LIVE_ADDR_CHANGE.
+func RejectWhileUp(iface FakeInterface, mac net.HardwareAddr) error {
+	if iface.Up {
+		return syscall.EBUSY
+	}
+	return nil
+}
+
+// RejectLocallyAdministered refuses addresses with the locally administered
+// bit set, as some drivers and firmwares do.
+func RejectLocallyAdministered(iface FakeInterface, mac net.HardwareAddr) error {
+	if len(mac) > 0 && mac[0]&2 != 0 {
+		return syscall.EADDRNOTAVAIL
+	}
+	return nil
+}
+
+// RejectPrefix returns a rule refusing the addresses starting with prefix.
+func RejectPrefix(prefix net.HardwareAddr) FakeRule {
+	return func(iface FakeInterface, mac net.HardwareAddr) error {
+		if len(mac) >= len(prefix) && CompareMacs(mac[:len(prefix)], prefix) {
+			return syscall.EADDRNOTAVAIL
+		}
+		return nil
+	}
+}
+
+// NewFakeBackend returns a FakeBackend simulating ifaces. Interfaces
+// without an index are numbered from 1, without a hardware type 6 byte
+// addresses are taken for Ethernet.
+func NewFakeBackend(ifaces ...FakeInterface) *FakeBackend {
+	fakeBackendCount.Lock()
+	fakeBackendCount.n++
+	id := fmt.Sprintf("fake:%d", fakeBackendCount.n)
+	fakeBackendCount.Unlock()
+	b := &FakeBackend{
+		id:          id,
+		driverRules: make(map[string][]FakeRule),
+		errors:      make(map[fakeErrorKey]error),
+		history:     make(map[string][]net.HardwareAddr),
+	}
+	for _, iface := range ifaces {
+		b.AddInterface(iface)
+	}
+	return b
+}
+
+// AddInterface adds or replaces the simulated interface iface.Name.
+func (b *FakeBackend) AddInterface(iface FakeInterface) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	iface.HardwareAddr = append(net.HardwareAddr(nil), iface.HardwareAddr...)
+	iface.PermHardwareAddr = append(net.HardwareAddr(nil), iface.PermHardwareAddr...)
+	if iface.HardwareType == 0 && len(iface.HardwareAddr) == 6 {
+		iface.HardwareType = ARPHRD_ETHER
+	}
+	if iface.MTU == 0 {
+		iface.MTU = 1500
+	}
+	for i, existing := range b.ifaces {
+		if existing.Name == iface.Name {
+			if iface.Index == 0 {
+				iface.Index = existing.Index
+			}
+			b.ifaces[i] = &iface
+			return
+		}
+	}
+	if iface.Index == 0 {
+		for _, existing := range b.ifaces {
+			if existing.Index > iface.Index {
+				iface.Index = existing.Index
+			}
+		}
+		iface.Index++
+	}
+	b.ifaces = append(b.ifaces, &iface)
+}
+
+// Interface returns a copy of the simulated interface called name.
+func (b *FakeBackend) Interface(name string) (iface FakeInterface, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, existing := range b.ifaces {
+		if existing.Name == name {
+			return *existing, true
+		}
+	}
+	return
+}
+
+// AddDriverRule makes every interface using driver refuse the changes
+// rule returns an error for.
+func (b *FakeBackend) AddDriverRule(driver string, rule FakeRule) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.driverRules[driver] = append(b.driverRules[driver], rule)
+}
+
+// InjectError makes op fail with err on the interface called name, or on
+// every interface when name is empty, until ClearErrors.
+func (b *FakeBackend) InjectError(op FakeOp, name string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errors[fakeErrorKey{op, name}] = err
+}
+
+func (b *FakeBackend) ClearErrors() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.errors = make(map[fakeErrorKey]error)
+}
+
+// SetCarrier plugs or unplugs the simulated cable of name.
+func (b *FakeBackend) SetCarrier(name string, carrier bool) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	iface, err := b.find(name)
+	if err != nil {
+		return
+	}
+	iface.NoCarrier = !carrier
+	return
+}
+
+// History returns the addresses successfully set on name, oldest first.
+func (b *FakeBackend) History(name string) []net.HardwareAddr {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]net.HardwareAddr(nil), b.history[name]...)
+}
+
+func (b *FakeBackend) Links() (links []Link, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	err = b.injected(FakeOpLinks, "")
+	if err != nil {
+		return
+	}
+	for _, iface := range b.ifaces {
+		links = append(links, iface.link())
+	}
+	return
+}
+
+func (b *FakeBackend) LinkByName(name string) (link Link, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	iface, err := b.find(name)
+	if err != nil {
+		return
+	}
+	err = b.injected(FakeOpLookup, name)
+	if err != nil {
+		return
+	}
+	link = iface.link()
+	return
+}
+
+func (b *FakeBackend) LinkByIndex(index int) (link Link, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, iface := range b.ifaces {
+		if iface.Index == index {
+			err = b.injected(FakeOpLookup, iface.Name)
+			if err != nil {
+				return
+			}
+			link = iface.link()
+			return
+		}
+	}
+	err = syscall.ENODEV
+	return
+}
+
+// SetHardwareAddr checks mac the way the kernel does for the interface
+// type, then runs the interface and driver rules.
+func (b *FakeBackend) SetHardwareAddr(link Link, mac net.HardwareAddr) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	iface, err := b.find(link.Name)
+	if err != nil {
+		return
+	}
+	err = b.injected(FakeOpSet, iface.Name)
+	if err != nil {
+		return
+	}
+	if len(mac) != len(iface.HardwareAddr) {
+		return syscall.EINVAL
+	}
+	if iface.HardwareType == ARPHRD_ETHER && (mac[0]&1 != 0 || isZeroMac(mac)) {
+		return syscall.EADDRNOTAVAIL
+	}
+	rules := append(append([]FakeRule(nil), iface.Rules...), b.driverRules[iface.Driver]...)
+	for _, rule := range rules {
+		err = rule(*iface, mac)
+		if err != nil {
+			return
+		}
+	}
+	iface.HardwareAddr = append(net.HardwareAddr(nil), mac...)
+	b.history[iface.Name] = append(b.history[iface.Name], iface.HardwareAddr)
+	return
+}
+
+func (b *FakeBackend) SetAdminState(link Link, up bool) (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	iface, err := b.find(link.Name)
+	if err != nil {
+		return
+	}
+	op := FakeOpDown
+	if up {
+		op = FakeOpUp
+	}
+	err = b.injected(op, iface.Name)
+	if err != nil {
+		return
+	}
+	iface.Up = up
+	return
+}
+
+func (b *FakeBackend) LinkDriver(link Link) (driver string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	iface, err := b.find(link.Name)
+	if err != nil {
+		return
+	}
+	driver = iface.Driver
+	return
+}
+
+// ClassifyLink reports the Kind the interface was given, an interface
+// without one is judged from its link alone.
+func (b *FakeBackend) ClassifyLink(link Link) (info InterfaceInfo, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	iface, err := b.find(link.Name)
+	if err != nil {
+		return
+	}
+	info = linkInfo(link)
+	info.Driver = iface.Driver
+	info.Kind = iface.Kind
+	if info.Kind == KindUnknown {
+		info.Kind = kindFromLink(link, iface.Driver)
+	}
+	info.Physical = info.Kind == KindEthernet || info.Kind == KindWireless
+	return
+}
+
+// PermanentHardwareAddr stands for ethtool and sysfs, which know nothing of
+// the simulated interfaces. The permanent address is already in the link.
+func (b *FakeBackend) PermanentHardwareAddr(link Link) (perm PermanentMac, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	err = b.injected(FakeOpPermAddr, link.Name)
+	if err == nil {
+		err = fmt.Errorf("No permanent MAC simulated for %s", link.Name)
+	}
+	return
+}
+
+// LinkNetwork returns the Network the interface was given.
+func (b *FakeBackend) LinkNetwork(link Link) (network Network, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	iface, err := b.find(link.Name)
+	if err == nil {
+		err = b.injected(FakeOpNetwork, link.Name)
+	}
+	if err != nil {
+		return
+	}
+	if iface.Network.GatewayMac == nil {
+		err = fmt.Errorf("No gateway simulated for %s", link.Name)
+		return
+	}
+	network = iface.Network
+	return
+}
+
+// SetNoNetAdmin makes the backend act as if it lacked CAP_NET_ADMIN.
+func (b *FakeBackend) SetNoNetAdmin(noNetAdmin bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.noNetAdmin = noNetAdmin
+}
+
+func (b *FakeBackend) HasNetAdmin() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.noNetAdmin, nil
+}
+
+func (b *FakeBackend) NamespaceID() string {
+	return b.id
+}
+
+func (b *FakeBackend) find(name string) (iface *FakeInterface, err error) {
+	for _, existing := range b.ifaces {
+		if existing.Name == name {
+			iface = existing
+			return
+		}
+	}
+	err = syscall.ENODEV
+	return
+}
+
+func (b *FakeBackend) injected(op FakeOp, name string) error {
+	if err, ok := b.errors[fakeErrorKey{op, name}]; ok {
+		return err
+	}
+	return b.errors[fakeErrorKey{op, ""}]
+}
+
+func (iface *FakeInterface) link() Link {
+	link := Link{
+		Index:            iface.Index,
+		Name:             iface.Name,
+		MTU:              iface.MTU,
+		HardwareType:     iface.HardwareType,
+		HardwareAddr:     append(net.HardwareAddr(nil), iface.HardwareAddr...),
+		PermHardwareAddr: append(net.HardwareAddr(nil), iface.PermHardwareAddr...),
+		OperState:        OperDown,
+	}
+	if iface.Kind == KindLoopback {
+		link.Flags |= net.FlagLoopback
+	}
+	if iface.Up {
+		link.Flags |= net.FlagUp
+		link.OperState = OperLowerLayerDown
+		if !iface.NoCarrier {
+			link.Flags |= net.FlagRunning
+			link.Carrier = true
+			link.OperState = OperUp
+		}
+	}
+	return link
+}
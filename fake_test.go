@@ -0,0 +1,144 @@
+package libmacouflage
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFakeBackend() *FakeBackend {
+	perm, _ := net.ParseMAC(OuiDb[0].VendorPrefix + ":12:34:56")
+	return NewFakeBackend(
+		FakeInterface{Name: "fake0", Kind: KindEthernet, Driver: "e1000e",
+			HardwareAddr: perm, PermHardwareAddr: perm},
+		FakeInterface{Name: "lo", Kind: KindLoopback, HardwareType: ARPHRD_LOOPBACK,
+			HardwareAddr: make(net.HardwareAddr, 6)},
+	)
+}
+
+func Test_FakeBackend_1(t *testing.T) {
+	h := NewHandle(newTestFakeBackend())
+	modes := map[string]func(string) (SpoofResult, error){
+		ModeRandom:  func(name string) (SpoofResult, error) { return h.SpoofMacRandom(name, false) },
+		ModeEnding:  func(name string) (SpoofResult, error) { return h.SpoofMacSameVendor(name, true) },
+		ModeAnother: h.SpoofMacSameDeviceType,
+		ModeAny:     h.SpoofMacAnyDeviceType,
+		ModePopular: h.SpoofMacPopular,
+	}
+	for mode, spoof := range modes {
+		result, err := spoof("fake0")
+		assert.NoError(t, err, mode)
+		assert.True(t, result.Changed, mode)
+		assert.Equal(t, mode, result.Mode)
+		assert.NoError(t, h.RevertMac("fake0"), mode)
+		changed, err := h.MacChanged("fake0")
+		assert.NoError(t, err, mode)
+		assert.False(t, changed, mode)
+	}
+}
+
+func Test_FakeBackend_2(t *testing.T) {
+	backend := newTestFakeBackend()
+	h := NewHandle(backend)
+	oldMac, _ := h.GetCurrentMac("fake0")
+	_, err := h.SpoofMacSameVendor("fake0", true)
+	assert.NoError(t, err)
+	newMac, _ := h.GetCurrentMac("fake0")
+	assert.Equal(t, oldMac[:3], newMac[:3])
+	assert.Len(t, backend.History("fake0"), 1)
+	_, err = h.SpoofMacRandom("lo", false)
+	assert.IsType(t, InvalidInterfaceTypeError{}, err)
+	ifaces, err := h.GetInterfaces()
+	assert.NoError(t, err)
+	assert.Len(t, ifaces, 1)
+}
+
+func Test_FakeBackend_3(t *testing.T) {
+	backend := newTestFakeBackend()
+	backend.AddDriverRule("e1000e", RejectWhileUp)
+	backend.AddInterface(FakeInterface{Name: "fake0", Kind: KindEthernet, Driver: "e1000e", Up: true,
+		HardwareAddr: net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55}})
+	h := NewHandle(backend)
+	_, err := h.SetMacWithOptions("fake0", "00:11:22:00:00:01", SetMacOptions{})
+	assert.Error(t, err, "Function failed to generate error for busy interface")
+	result, err := h.SetMacWithOptions("fake0", "00:11:22:00:00:01", SetMacOptions{AutoDown: true})
+	assert.NoError(t, err)
+	assert.False(t, result.LiveChange)
+	iface, _ := backend.Interface("fake0")
+	assert.True(t, iface.Up, "Admin state was not restored")
+	assert.Equal(t, "00:11:22:00:00:01", iface.HardwareAddr.String())
+}
+
+func Test_FakeBackend_4(t *testing.T) {
+	backend := newTestFakeBackend()
+	backend.AddDriverRule("e1000e", RejectLocallyAdministered)
+	h := NewHandle(backend)
+	_, err := h.SpoofMacRandom("fake0", false)
+	assert.True(t, errors.Is(err, syscall.EADDRNOTAVAIL))
+	_, err = h.SpoofMacRandom("fake0", true)
+	assert.NoError(t, err)
+	err = h.SetMac("fake0", "01:00:5e:00:00:01")
+	assert.True(t, errors.Is(err, syscall.EADDRNOTAVAIL), "Multicast address accepted")
+}
+
+func Test_FakeBackend_5(t *testing.T) {
+	backend := newTestFakeBackend()
+	h := NewHandle(backend)
+	backend.InjectError(FakeOpSet, "fake0", syscall.EIO)
+	_, err := h.SpoofMacRandom("fake0", true)
+	assert.True(t, errors.Is(err, syscall.EIO))
+	backend.ClearErrors()
+	backend.InjectError(FakeOpLinks, "", syscall.ENOBUFS)
+	_, err = h.GetInterfaces()
+	assert.True(t, errors.Is(err, syscall.ENOBUFS))
+	backend.ClearErrors()
+	backend.SetNoNetAdmin(true)
+	err = h.SetMac("fake0", "00:11:22:00:00:01")
+	assert.IsType(t, MissingCapabilityError{}, err)
+}
+
+func Test_FakeBackend_6(t *testing.T) {
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	backend := NewFakeBackend(FakeInterface{Name: "fake0", HardwareAddr: mac})
+	h := NewHandle(backend)
+	_, err := h.GetPermanentMacInfo("fake0")
+	assert.Error(t, err, "Function failed to generate error without permanent address")
+	assert.True(t, strings.Contains(err.Error(), "No permanent MAC simulated"))
+	assert.NoError(t, h.SetMac("fake0", "00:11:22:00:00:01"))
+	perm, err := h.GetPermanentMacInfo("fake0")
+	assert.NoError(t, err)
+	assert.Equal(t, PermAddrRecorded, perm.Source)
+	assert.Equal(t, mac, perm.HardwareAddr)
+	other := NewHandle(NewFakeBackend(FakeInterface{Name: "fake0", HardwareAddr: mac}))
+	_, err = other.GetPermanentMacInfo("fake0")
+	assert.Error(t, err, "Recorded address leaked to another fake backend")
+}
+
+func Test_FakeBackend_7(t *testing.T) {
+	backend := newTestFakeBackend()
+	backend.AddInterface(FakeInterface{Name: "fake0", Up: true, NoCarrier: true,
+		HardwareAddr: net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55}})
+	link, err := backend.LinkByName("fake0")
+	assert.NoError(t, err)
+	assert.Equal(t, OperLowerLayerDown, link.OperState)
+	assert.NoError(t, backend.SetCarrier("fake0", true))
+	link, _ = backend.LinkByName("fake0")
+	assert.True(t, link.IsReady())
+	_, err = backend.LinkByName("missing0")
+	assert.Equal(t, syscall.ENODEV, err)
+}
+
+func Test_FakeBackend_8(t *testing.T) {
+	backend := newTestFakeBackend()
+	backend.AddInterface(FakeInterface{Name: "fake1", Kind: KindEthernet,
+		HardwareAddr: net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55}})
+	h := NewHandle(backend)
+	macs, err := h.GetAllPermanentMacs()
+	assert.True(t, errors.Is(err, ErrPermAddrUnsupported))
+	iface, _ := backend.Interface("fake0")
+	assert.Equal(t, map[string]string{"fake0": iface.PermHardwareAddr.String()}, macs)
+}
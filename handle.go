@@ -0,0 +1,57 @@
+package libmacouflage
+
+// Handle runs the package operations against a particular backend and
+// network namespace. The package level functions use a Handle built from
+// the backend set with SetLinkBackend and the caller's namespace.
+type Handle struct {
+	backend LinkBackend
+	ns      NetNS
+	nsID    string
+	policy  InterfacePolicy
+}
+
+// NamespaceBackend is implemented by backends whose links live apart from
+// the caller's namespace, such as simulated ones. The ID keeps their
+// recorded original addresses apart.
+type NamespaceBackend interface {
+	NamespaceID() string
+}
+
+// NewHandle returns a Handle that uses backend in the caller's namespace.
+func NewHandle(backend LinkBackend) *Handle {
+	h := &Handle{backend: backend}
+	if nsBackend, ok := backend.(NamespaceBackend); ok {
+		h.nsID = nsBackend.NamespaceID()
+	}
+	return h
+}
+
+// NewHandleAt returns a Handle that operates on the interfaces of the
+// network namespace ns through rtnetlink.
+func NewHandleAt(ns NetNS) (h *Handle, err error) {
+	nsID, err := ns.ID()
+	if err != nil {
+		return
+	}
+	h = &Handle{backend: NetlinkBackend{NetNS: ns}, ns: ns, nsID: nsID}
+	return
+}
+
+func defaultHandle() *Handle {
+	return NewHandle(linkBackend)
+}
+
+// lookup finds the link called name, with a structured error
+func (h *Handle) lookup(name string) (link Link, err error) {
+	link, err = h.backend.LinkByName(name)
+	err = linkError("lookup", name, err)
+	return
+}
+
+func (h *Handle) Backend() LinkBackend {
+	return h.backend
+}
+
+func (h *Handle) NetNS() NetNS {
+	return h.ns
+}
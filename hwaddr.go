@@ -0,0 +1,163 @@
+package libmacouflage
+
+import (
+	"fmt"
+	"net"
+)
+
+// ARPHRD_* hardware types from linux/if_arp.h
+const (
+	ARPHRD_ETHER      = 1
+	ARPHRD_IEEE1394   = 24
+	ARPHRD_INFINIBAND = 32
+	ARPHRD_LOOPBACK   = 772
+	ARPHRD_IEEE80211  = 801
+	ARPHRD_IEEE802154 = 804
+	ARPHRD_NONE       = 0xfffe
+)
+
+// sa_data of the struct sockaddr SIOCSIFHWADDR takes
+const sizeofSockaddrData = 14
+
+// AddressFormat describes the hardware address of an interface type. The
+// randomized part is the IEEE EUI-48 or EUI-64 found at EUIOffset, whose
+// first three bytes are the OUI; EUIOffset is -1 when there is none.
+type AddressFormat struct {
+	Name      string
+	Type      uint16
+	Len       int
+	EUIOffset int
+	Settable  bool
+}
+
+type UnsupportedAddressError struct {
+	msg string
+}
+
+// An InfiniBand address is 4 bytes of flags and QPN then the port GID, a
+// 64-bit subnet prefix and the port GUID. The kernel only lets the GUID
+// change. FireWire addresses carry the node GUID and cannot be changed.
+var addressFormats = []AddressFormat{
+	{"ethernet", ARPHRD_ETHER, 6, 0, true},
+	{"ieee802.11", ARPHRD_IEEE80211, 6, 0, true},
+	{"ieee802.15.4", ARPHRD_IEEE802154, 8, 0, true},
+	{"infiniband", ARPHRD_INFINIBAND, 20, 12, true},
+	{"firewire", ARPHRD_IEEE1394, 16, -1, false},
+}
+
+func (e UnsupportedAddressError) Error() string {
+	return e.msg
+}
+
+// AddressFormatOf returns the address format of link. Backends that cannot
+// tell the hardware type leave it zero, the address length decides then.
+// Unknown types with an address can still be set but not randomized.
+func AddressFormatOf(link Link) (format AddressFormat, err error) {
+	addrLen := len(link.HardwareAddr)
+	if addrLen == 0 {
+		msg := fmt.Sprintf("%s has no hardware address", link.Name)
+		err = UnsupportedAddressError{msg}
+		return
+	}
+	for _, f := range addressFormats {
+		if f.Type == link.HardwareType || (link.HardwareType == 0 && f.Len == addrLen) {
+			format = f
+			break
+		}
+	}
+	if format.Name == "" {
+		format = AddressFormat{fmt.Sprintf("arphrd %d", link.HardwareType), link.HardwareType, addrLen, -1, true}
+	}
+	// The kernel knows best, an unexpected length leaves nothing to randomize
+	if format.Len != addrLen {
+		format.Len = addrLen
+		format.EUIOffset = -1
+	}
+	return
+}
+
+// Check tells whether addr can be set on an interface of this format.
+func (f AddressFormat) Check(addr net.HardwareAddr) (err error) {
+	if !f.Settable {
+		msg := fmt.Sprintf("Hardware address of %s interfaces cannot be changed", f.Name)
+		err = UnsupportedAddressError{msg}
+		return
+	}
+	if len(addr) != f.Len {
+		msg := fmt.Sprintf("Invalid address length for %s interface: %d, expected %d", f.Name, len(addr), f.Len)
+		err = UnsupportedAddressError{msg}
+	}
+	return
+}
+
+// EUI returns the part of addr that is an IEEE EUI-48 or EUI-64, nil if
+// the format has none.
+func (f AddressFormat) EUI(addr net.HardwareAddr) net.HardwareAddr {
+	if f.EUIOffset < 0 || len(addr) != f.Len {
+		return nil
+	}
+	return addr[f.EUIOffset:]
+}
+
+// Randomize returns a copy of addr with its EUI randomized from byte start
+// on, as RandomizeMac does. The rest of the address is kept.
+func (f AddressFormat) Randomize(addr net.HardwareAddr, start int, bia bool) (mac net.HardwareAddr, err error) {
+	mac = append(net.HardwareAddr(nil), addr...)
+	eui := f.EUI(mac)
+	if eui == nil {
+		msg := fmt.Sprintf("Cannot randomize the hardware address of %s interfaces", f.Name)
+		err = UnsupportedAddressError{msg}
+		return
+	}
+	_, err = RandomizeMac(eui, start, bia)
+	return
+}
+
+// RandomizePrefix returns a copy of addr with its EUI randomized but for the
+// first bits, as RandomizeMacPrefix does.
+func (f AddressFormat) RandomizePrefix(addr net.HardwareAddr, bits int, bia bool) (mac net.HardwareAddr, err error) {
+	mac = append(net.HardwareAddr(nil), addr...)
+	eui := f.EUI(mac)
+	if eui == nil {
+		msg := fmt.Sprintf("Cannot randomize the hardware address of %s interfaces", f.Name)
+		err = UnsupportedAddressError{msg}
+		return
+	}
+	_, err = RandomizeMacPrefix(eui, bits, bia)
+	return
+}
+
+// WithVendor returns a copy of addr whose EUI starts with the vendor prefix,
+// in the notation of ParsePrefix, and ends randomly, with the burned-in
+// address bit.
+func (f AddressFormat) WithVendor(addr net.HardwareAddr, prefix string) (mac net.HardwareAddr, err error) {
+	vendor, bits, err := ParsePrefix(prefix)
+	if err != nil {
+		return
+	}
+	mac = append(net.HardwareAddr(nil), addr...)
+	eui := f.EUI(mac)
+	if eui == nil {
+		msg := fmt.Sprintf("Cannot set a vendor prefix on %s interfaces", f.Name)
+		err = UnsupportedAddressError{msg}
+		return
+	}
+	if len(vendor) > len(eui) {
+		err = fmt.Errorf("Vendor prefix %s is too long for %s addresses", prefix, f.Name)
+		return
+	}
+	applyPrefix(eui, vendor, bits)
+	_, err = RandomizeMacPrefix(eui, bits, true)
+	return
+}
+
+// addressFormat returns the link called name, if the interface policy
+// accepts it, and its address format
+func (h *Handle) addressFormat(name string) (link Link, format AddressFormat, err error) {
+	link, err = h.checkInterface(name)
+	if err != nil {
+		return
+	}
+	format, err = AddressFormatOf(link)
+	return
+}
@@ -0,0 +1,80 @@
+package libmacouflage
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AddressFormatOf_1(t *testing.T) {
+	ib, _ := net.ParseMAC("00:00:04:04:fe:80:00:00:00:00:00:00:00:02:c9:03:00:0a:bc:de")
+	format, err := AddressFormatOf(Link{Name: "ib0", HardwareType: ARPHRD_INFINIBAND, HardwareAddr: ib})
+	assert.NoError(t, err)
+	assert.Equal(t, "infiniband", format.Name)
+	assert.Equal(t, 20, format.Len)
+	assert.Equal(t, "00:02:c9:03:00:0a:bc:de", format.EUI(ib).String())
+}
+
+func Test_AddressFormatOf_2(t *testing.T) {
+	eui64, _ := net.ParseMAC("00:12:4b:00:01:02:03:04")
+	format, err := AddressFormatOf(Link{Name: "wpan0", HardwareAddr: eui64})
+	assert.NoError(t, err)
+	assert.Equal(t, "ieee802.15.4", format.Name, "Format not chosen by length for an unknown type")
+	_, err = AddressFormatOf(Link{Name: "tun0", HardwareType: ARPHRD_NONE})
+	assert.Error(t, err, "Function failed to generate error for interface without address")
+}
+
+func Test_AddressFormat_Check_1(t *testing.T) {
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	eui64, _ := net.ParseMAC("00:12:4b:00:01:02:03:04")
+	format, _ := AddressFormatOf(Link{HardwareType: ARPHRD_ETHER, HardwareAddr: mac})
+	assert.NoError(t, format.Check(mac))
+	assert.Error(t, format.Check(eui64), "Function failed to generate error for wrong length")
+	firewire := make(net.HardwareAddr, 16)
+	format, _ = AddressFormatOf(Link{HardwareType: ARPHRD_IEEE1394, HardwareAddr: firewire})
+	assert.Error(t, format.Check(firewire), "Function failed to generate error for FireWire")
+}
+
+func Test_AddressFormat_Randomize_1(t *testing.T) {
+	ib, _ := net.ParseMAC("00:00:04:04:fe:80:00:00:00:00:00:00:00:02:c9:03:00:0a:bc:de")
+	format, _ := AddressFormatOf(Link{HardwareType: ARPHRD_INFINIBAND, HardwareAddr: ib})
+	mac, err := format.Randomize(ib, 0, false)
+	assert.NoError(t, err)
+	assert.Equal(t, ib[:12], mac[:12], "Subnet prefix and QPN changed")
+	assert.Equal(t, byte(2), mac[12]&3, "GUID is not locally administered unicast")
+	assert.Equal(t, "00:02:c9:03:00:0a:bc:de", format.EUI(ib).String(), "Original address modified")
+	mac, err = format.WithVendor(ib, "00:02:C9")
+	assert.NoError(t, err)
+	assert.Equal(t, "00:02:c9", mac[12:15].String())
+}
+
+func Test_AddressFormat_Randomize_2(t *testing.T) {
+	addr := net.HardwareAddr{1, 2, 3, 4}
+	format, _ := AddressFormatOf(Link{HardwareType: 0x1234, HardwareAddr: addr})
+	assert.NoError(t, format.Check(addr))
+	_, err := format.Randomize(addr, 0, true)
+	assert.IsType(t, UnsupportedAddressError{}, err)
+}
+
+func Test_RandomizeMac_EUI64_1(t *testing.T) {
+	bytes := []byte{0x00, 0x12, 0x4b, 0, 0, 0, 0, 0}
+	mac, err := RandomizeMac(bytes, 3, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "00:12:4b", mac[:3].String())
+}
+
+func Test_SetMacWithOptions_AddressLength_1(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	backend.link.Flags = 0
+	h := NewHandle(backend)
+	_, err := h.SetMacWithOptions("rec0", "00:12:4b:00:01:02:03:04", SetMacOptions{})
+	assert.IsType(t, UnsupportedAddressError{}, err)
+	assert.Empty(t, backend.calls)
+}
+
+func Test_IoctlBackend_SetHardwareAddr_1(t *testing.T) {
+	ib := make(net.HardwareAddr, 20)
+	err := IoctlBackend{}.SetHardwareAddr(Link{Name: "ib0", HardwareType: ARPHRD_INFINIBAND}, ib)
+	assert.IsType(t, UnsupportedAddressError{}, err)
+}
@@ -0,0 +1,368 @@
+package libmacouflage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tun_flags bit of a tap device, from linux/if_tun.h
+const IFF_TAP = 0x0002
+
+// InterfaceKind is what an interface is, as far as the classifier can tell.
+type InterfaceKind int
+
+const (
+	KindUnknown InterfaceKind = iota
+	KindEthernet
+	KindWireless
+	KindBridge
+	KindBond
+	KindVlan
+	KindMacvlan
+	KindMacvtap
+	KindTap
+	KindTun
+	KindVeth
+	KindLoopback
+	KindVirtual
+	KindInfiniband
+	KindWpan
+	KindFirewire
+	KindOther
+)
+
+// InterfaceInfo is what was found out about an interface to classify it.
+// Physical is set when a device backs the interface.
+type InterfaceInfo struct {
+	Name     string
+	Kind     InterfaceKind
+	ArpType  int
+	DevType  string
+	Driver   string
+	Physical bool
+}
+
+// ClassifierBackend is implemented by backends that can tell what kind of
+// interface a link is.
+type ClassifierBackend interface {
+	ClassifyLink(link Link) (InterfaceInfo, error)
+}
+
+// InterfacePolicy decides which interfaces GetInterfaces lists and the MAC
+// functions accept.
+type InterfacePolicy func(info InterfaceInfo) bool
+
+var kindNames = map[InterfaceKind]string{
+	KindUnknown:    "unknown",
+	KindEthernet:   "ethernet",
+	KindWireless:   "wireless",
+	KindBridge:     "bridge",
+	KindBond:       "bond",
+	KindVlan:       "vlan",
+	KindMacvlan:    "macvlan",
+	KindMacvtap:    "macvtap",
+	KindTap:        "tap",
+	KindTun:        "tun",
+	KindVeth:       "veth",
+	KindLoopback:   "loopback",
+	KindVirtual:    "virtual",
+	KindInfiniband: "infiniband",
+	KindWpan:       "wpan",
+	KindFirewire:   "firewire",
+	KindOther:      "other",
+}
+
+// Kinds of the non-Ethernet hardware types
+var arpTypeKinds = map[int]InterfaceKind{
+	ARPHRD_INFINIBAND: KindInfiniband,
+	ARPHRD_IEEE802154: KindWpan,
+	ARPHRD_IEEE1394:   KindFirewire,
+}
+
+// Kinds announced in the DEVTYPE line of uevent
+var devTypeKinds = map[string]InterfaceKind{
+	"wlan":    KindWireless,
+	"bridge":  KindBridge,
+	"bond":    KindBond,
+	"vlan":    KindVlan,
+	"macvlan": KindMacvlan,
+	"macvtap": KindMacvtap,
+}
+
+// Kinds of the virtual drivers that set no DEVTYPE, tun is told apart by
+// its tun_flags
+var driverKinds = map[string]InterfaceKind{
+	"veth":                KindVeth,
+	"bridge":              KindBridge,
+	"bonding":             KindBond,
+	"802.1Q VLAN Support": KindVlan,
+	"macvlan":             KindMacvlan,
+}
+
+// DefaultInterfacePolicy refuses the interfaces without a hardware address
+// to change.
+var DefaultInterfacePolicy = DenyKinds(KindLoopback, KindTun, KindOther)
+
+// PhysicalInterfacePolicy only accepts network cards.
+var PhysicalInterfacePolicy = AllowKinds(KindEthernet, KindWireless)
+
+var interfacePolicy = DefaultInterfacePolicy
+
+func (k InterfaceKind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+func (k InterfaceKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *InterfaceKind) UnmarshalText(text []byte) error {
+	for kind, name := range kindNames {
+		if name == string(text) {
+			*k = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown interface kind %q", text)
+}
+
+// AllowKinds returns a policy that accepts only the given kinds.
+func AllowKinds(kinds ...InterfaceKind) InterfacePolicy {
+	return func(info InterfaceInfo) bool {
+		for _, kind := range kinds {
+			if info.Kind == kind {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// DenyKinds returns a policy that accepts everything but the given kinds.
+func DenyKinds(kinds ...InterfaceKind) InterfacePolicy {
+	allow := AllowKinds(kinds...)
+	return func(info InterfaceInfo) bool {
+		return !allow(info)
+	}
+}
+
+// SetInterfacePolicy changes the policy of the package level functions and
+// of handles without a policy of their own.
+func SetInterfacePolicy(policy InterfacePolicy) {
+	interfacePolicy = policy
+}
+
+func GetInterfacePolicy() InterfacePolicy {
+	return interfacePolicy
+}
+
+// SetInterfacePolicy gives the handle its own policy, nil goes back to the
+// package one.
+func (h *Handle) SetInterfacePolicy(policy InterfacePolicy) {
+	h.policy = policy
+}
+
+func (h *Handle) interfacePolicy() InterfacePolicy {
+	if h.policy != nil {
+		return h.policy
+	}
+	return interfacePolicy
+}
+
+func ClassifyInterface(name string) (info InterfaceInfo, err error) {
+	return defaultHandle().ClassifyInterface(name)
+}
+
+func (h *Handle) ClassifyInterface(name string) (info InterfaceInfo, err error) {
+	link, err := h.lookup(name)
+	if err != nil {
+		return
+	}
+	info = h.classifyLink(link)
+	return
+}
+
+// classifyLink never fails, what the backend cannot classify is judged
+// from the link alone
+func (h *Handle) classifyLink(link Link) (info InterfaceInfo) {
+	if classifier, ok := h.backend.(ClassifierBackend); ok {
+		var err error
+		info, err = classifier.ClassifyLink(link)
+		if err == nil {
+			return
+		}
+	}
+	info = linkInfo(link)
+	info.Kind = kindFromLink(link, "")
+	return
+}
+
+func (h *Handle) interfaceAllowed(link Link) (info InterfaceInfo, allowed bool) {
+	info = h.classifyLink(link)
+	allowed = h.interfacePolicy()(info)
+	return
+}
+
+// checkInterface returns the link called name if the policy accepts it
+func (h *Handle) checkInterface(name string) (link Link, err error) {
+	link, err = h.lookup(name)
+	if err != nil {
+		return
+	}
+	info, allowed := h.interfaceAllowed(link)
+	if !allowed {
+		msg := "Invalid interface type: " + name + " (" + info.Kind.String() + ")"
+		err = InvalidInterfaceTypeError{msg, name, info.Kind}
+	}
+	return
+}
+
+func (b NetlinkBackend) ClassifyLink(link Link) (InterfaceInfo, error) {
+	return classifyLink(b.NetNS, link)
+}
+
+func (b IoctlBackend) ClassifyLink(link Link) (InterfaceInfo, error) {
+	return classifyLink(b.NetNS, link)
+}
+
+// classifyLink reads sysfs, which only describes the namespace it was
+// mounted from. Links of other namespaces are classified by their driver.
+func classifyLink(ns NetNS, link Link) (info InterfaceInfo, err error) {
+	if !ns.IsCurrent() {
+		info = linkInfo(link)
+		info.Driver, _ = getEthtoolDriver(ns, link.Name)
+		info.Kind = kindFromLink(link, info.Driver)
+		return
+	}
+	info, tunFlags, err := readSysfsInterface(link.Name)
+	if err != nil {
+		return
+	}
+	if info.Driver == "" {
+		info.Driver, _ = getEthtoolDriver(ns, link.Name)
+	}
+	info.Kind = kindFromSysfs(info, tunFlags, link.Flags&net.FlagLoopback != 0)
+	return
+}
+
+// readSysfsInterface gathers type, uevent DEVTYPE, the device symlink,
+// wireless/ and the driver of a device backed interface. tunFlags is -1
+// unless the interface is a tun or tap device.
+func readSysfsInterface(name string) (info InterfaceInfo, tunFlags int, err error) {
+	dir := filepath.Join(sysfsNetDir, name)
+	info.Name = name
+	info.ArpType, err = readSysfsInt(filepath.Join(dir, "type"), 10)
+	if err != nil {
+		return
+	}
+	if uevent, ueventErr := ioutil.ReadFile(filepath.Join(dir, "uevent")); ueventErr == nil {
+		for _, line := range strings.Split(string(uevent), "\n") {
+			if strings.HasPrefix(line, "DEVTYPE=") {
+				info.DevType = strings.TrimPrefix(line, "DEVTYPE=")
+			}
+		}
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "wireless")); statErr == nil {
+		info.DevType = "wlan"
+	} else if _, statErr := os.Stat(filepath.Join(dir, "phy80211")); statErr == nil {
+		info.DevType = "wlan"
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "device")); statErr == nil {
+		info.Physical = true
+		if driver, linkErr := os.Readlink(filepath.Join(dir, "device", "driver")); linkErr == nil {
+			info.Driver = filepath.Base(driver)
+		}
+	}
+	tunFlags, tunErr := readSysfsInt(filepath.Join(dir, "tun_flags"), 0)
+	if tunErr != nil {
+		tunFlags = -1
+	}
+	return
+}
+
+func kindFromSysfs(info InterfaceInfo, tunFlags int, loopback bool) InterfaceKind {
+	if loopback || info.ArpType == ARPHRD_LOOPBACK {
+		return KindLoopback
+	}
+	if kind, ok := devTypeKinds[info.DevType]; ok {
+		return kind
+	}
+	if tunFlags >= 0 {
+		if tunFlags&IFF_TAP != 0 {
+			return KindTap
+		}
+		return KindTun
+	}
+	if info.Driver == "tun" {
+		if info.ArpType == ARPHRD_ETHER {
+			return KindTap
+		}
+		return KindTun
+	}
+	if info.ArpType == ARPHRD_NONE {
+		return KindTun
+	}
+	if info.ArpType != ARPHRD_ETHER {
+		if kind, ok := arpTypeKinds[info.ArpType]; ok {
+			return kind
+		}
+		return KindOther
+	}
+	if kind, ok := driverKinds[info.Driver]; ok {
+		return kind
+	}
+	if info.Physical {
+		return KindEthernet
+	}
+	return KindVirtual
+}
+
+// linkInfo is the start of a classification that cannot use sysfs, ArpType
+// is -1 when the backend cannot tell the hardware type
+func linkInfo(link Link) (info InterfaceInfo) {
+	info = InterfaceInfo{Name: link.Name, ArpType: -1}
+	if link.HardwareType != 0 {
+		info.ArpType = int(link.HardwareType)
+	}
+	return
+}
+
+func kindFromLink(link Link, driver string) InterfaceKind {
+	if link.Flags&net.FlagLoopback != 0 {
+		return KindLoopback
+	}
+	if link.HardwareType == ARPHRD_NONE {
+		return KindTun
+	}
+	if kind, ok := arpTypeKinds[int(link.HardwareType)]; ok {
+		return kind
+	}
+	if driver == "tun" {
+		if len(link.HardwareAddr) == 0 {
+			return KindTun
+		}
+		return KindTap
+	}
+	if kind, ok := driverKinds[driver]; ok {
+		return kind
+	}
+	return KindUnknown
+}
+
+func readSysfsInt(path string, base int) (value int, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	parsed, err := strconv.ParseInt(strings.TrimSpace(string(data)), base, 64)
+	value = int(parsed)
+	return
+}
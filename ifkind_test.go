@@ -0,0 +1,116 @@
+package libmacouflage
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sysfsInterface builds /sys/class/net/<name> in dir from files and
+// directories, the entries named "->target" become symlinks
+func sysfsInterface(t *testing.T, dir string, name string, files map[string]string) {
+	for file, content := range files {
+		path := filepath.Join(dir, name, file)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		switch {
+		case content == "/":
+			assert.NoError(t, os.MkdirAll(path, 0755))
+		case strings.HasPrefix(content, "->"):
+			assert.NoError(t, os.Symlink(content[2:], path))
+		default:
+			assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+		}
+	}
+}
+
+func withFakeSysfs(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "sysfs")
+	assert.NoError(t, err)
+	oldDir := sysfsNetDir
+	sysfsNetDir = dir
+	return dir, func() {
+		sysfsNetDir = oldDir
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_ClassifyLink_1(t *testing.T) {
+	dir, cleanup := withFakeSysfs(t)
+	defer cleanup()
+	devices := filepath.Join(dir, "devices")
+	assert.NoError(t, os.MkdirAll(filepath.Join(devices, "drivers", "e1000e"), 0755))
+	assert.NoError(t, os.MkdirAll(filepath.Join(devices, "pci0"), 0755))
+	assert.NoError(t, os.Symlink(filepath.Join(devices, "drivers", "e1000e"), filepath.Join(devices, "pci0", "driver")))
+	sysfsInterface(t, dir, "mcfnic0", map[string]string{"type": "1\n", "uevent": "INTERFACE=mcfnic0\n", "device": "->" + filepath.Join(devices, "pci0")})
+	sysfsInterface(t, dir, "tunnel0", map[string]string{"type": "1\n", "uevent": "INTERFACE=tunnel0\n", "device": "->" + filepath.Join(devices, "pci0")})
+	sysfsInterface(t, dir, "mcfwl0", map[string]string{"type": "1\n", "uevent": "DEVTYPE=wlan\n", "wireless": "/"})
+	sysfsInterface(t, dir, "mcfbr0", map[string]string{"type": "1\n", "uevent": "DEVTYPE=bridge\n"})
+	sysfsInterface(t, dir, "mcftap0", map[string]string{"type": "1\n", "uevent": "DEVTYPE=tun\n", "tun_flags": "0x1002\n"})
+	sysfsInterface(t, dir, "mcftun0", map[string]string{"type": "65534\n", "uevent": "DEVTYPE=tun\n", "tun_flags": "0x1001\n"})
+	sysfsInterface(t, dir, "mcflo", map[string]string{"type": "772\n", "uevent": ""})
+	sysfsInterface(t, dir, "mcfppp0", map[string]string{"type": "512\n", "uevent": ""})
+	sysfsInterface(t, dir, "mcfdummy0", map[string]string{"type": "1\n", "uevent": ""})
+	expected := map[string]InterfaceKind{
+		"mcfnic0":   KindEthernet,
+		"tunnel0":   KindEthernet,
+		"mcfwl0":    KindWireless,
+		"mcfbr0":    KindBridge,
+		"mcftap0":   KindTap,
+		"mcftun0":   KindTun,
+		"mcflo":     KindLoopback,
+		"mcfppp0":   KindOther,
+		"mcfdummy0": KindVirtual,
+	}
+	for name, kind := range expected {
+		info, err := classifyLink(NetNS{}, Link{Name: name})
+		assert.NoError(t, err)
+		assert.Equal(t, kind, info.Kind, name)
+	}
+	info, _ := classifyLink(NetNS{}, Link{Name: "mcfnic0"})
+	assert.Equal(t, "e1000e", info.Driver)
+	assert.True(t, info.Physical)
+}
+
+func Test_ClassifyLink_2(t *testing.T) {
+	_, cleanup := withFakeSysfs(t)
+	defer cleanup()
+	_, err := classifyLink(NetNS{}, Link{Name: "mcfmissing0"})
+	assert.Error(t, err, "Function failed to generate error for missing interface")
+}
+
+func Test_InterfacePolicy_1(t *testing.T) {
+	assert.True(t, DefaultInterfacePolicy(InterfaceInfo{Kind: KindTap}))
+	assert.True(t, DefaultInterfacePolicy(InterfaceInfo{Kind: KindUnknown}))
+	assert.False(t, DefaultInterfacePolicy(InterfaceInfo{Kind: KindLoopback}))
+	assert.True(t, PhysicalInterfacePolicy(InterfaceInfo{Kind: KindWireless}))
+	assert.False(t, PhysicalInterfacePolicy(InterfaceInfo{Kind: KindBridge}))
+}
+
+func Test_InterfacePolicy_2(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	h := NewHandle(backend)
+	h.SetInterfacePolicy(PhysicalInterfacePolicy)
+	_, err := h.SetMacWithOptions("rec0", "00:11:22:00:00:01", SetMacOptions{})
+	assert.IsType(t, InvalidInterfaceTypeError{}, err)
+	assert.Empty(t, backend.calls)
+	ifaces, err := h.GetInterfaces()
+	assert.NoError(t, err)
+	assert.Empty(t, ifaces)
+	assert.True(t, h.IsInterfaceTypeInvalid("rec0"))
+	assert.True(t, h.IsInterfaceTypeInvalid("missing0"))
+	h.SetInterfacePolicy(DefaultInterfacePolicy)
+	assert.False(t, h.IsInterfaceTypeInvalid("rec0"))
+}
+
+func Test_ClassifyInterface_1(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	backend.link.Flags |= net.FlagLoopback
+	info, err := NewHandle(backend).ClassifyInterface("rec0")
+	assert.NoError(t, err)
+	assert.Equal(t, KindLoopback, info.Kind)
+}
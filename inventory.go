@@ -0,0 +1,105 @@
+package libmacouflage
+
+import (
+	"encoding/json"
+	"net"
+)
+
+// InventoryEntry describes an interface of the system. Allowed is set when
+// the interface policy lets its MAC be changed, PermanentMac is only looked
+// up for those.
+type InventoryEntry struct {
+	Interface    string
+	Kind         InterfaceKind
+	Driver       string
+	Physical     bool
+	Up           bool
+	Allowed      bool
+	CurrentMac   net.HardwareAddr
+	PermanentMac net.HardwareAddr
+}
+
+type inventoryEntryJSON struct {
+	Interface    string        `json:"interface"`
+	Kind         InterfaceKind `json:"kind"`
+	Driver       string        `json:"driver,omitempty"`
+	Physical     bool          `json:"physical"`
+	Up           bool          `json:"up"`
+	Allowed      bool          `json:"allowed"`
+	CurrentMac   string        `json:"current_mac"`
+	PermanentMac string        `json:"permanent_mac,omitempty"`
+}
+
+func Inventory() (entries []InventoryEntry, err error) {
+	return defaultHandle().Inventory()
+}
+
+// Inventory lists every interface, including those the interface policy
+// refuses.
+func (h *Handle) Inventory() (entries []InventoryEntry, err error) {
+	links, err := h.backend.Links()
+	if err != nil {
+		err = linkError("list", "", err)
+		return
+	}
+	for _, link := range links {
+		info, allowed := h.interfaceAllowed(link)
+		entry := InventoryEntry{
+			Interface:  link.Name,
+			Kind:       info.Kind,
+			Driver:     info.Driver,
+			Physical:   info.Physical,
+			Up:         link.IsUp(),
+			Allowed:    allowed,
+			CurrentMac: link.HardwareAddr,
+		}
+		if entry.Allowed {
+			// Interfaces without a known permanent MAC are listed anyway
+			entry.PermanentMac, _ = h.GetPermanentMac(link.Name)
+		}
+		entries = append(entries, entry)
+	}
+	return
+}
+
+func (e InventoryEntry) MarshalJSON() ([]byte, error) {
+	j := inventoryEntryJSON{
+		Interface:  e.Interface,
+		Kind:       e.Kind,
+		Driver:     e.Driver,
+		Physical:   e.Physical,
+		Up:         e.Up,
+		Allowed:    e.Allowed,
+		CurrentMac: e.CurrentMac.String(),
+	}
+	if e.PermanentMac != nil {
+		j.PermanentMac = e.PermanentMac.String()
+	}
+	return json.Marshal(j)
+}
+
+func (e *InventoryEntry) UnmarshalJSON(data []byte) (err error) {
+	var j inventoryEntryJSON
+	err = json.Unmarshal(data, &j)
+	if err != nil {
+		return
+	}
+	*e = InventoryEntry{
+		Interface: j.Interface,
+		Kind:      j.Kind,
+		Driver:    j.Driver,
+		Physical:  j.Physical,
+		Up:        j.Up,
+		Allowed:   j.Allowed,
+	}
+	if j.CurrentMac != "" {
+		e.CurrentMac, err = net.ParseMAC(j.CurrentMac)
+		if err != nil {
+			return
+		}
+	}
+	if j.PermanentMac != "" {
+		e.PermanentMac, err = net.ParseMAC(j.PermanentMac)
+	}
+	return
+}
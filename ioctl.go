@@ -0,0 +1,203 @@
+package libmacouflage
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"unsafe"
+)
+
+// IoctlBackend uses the legacy SIOCSIFHWADDR ioctl, which addresses
+// interfaces by name.
+type IoctlBackend struct {
+	NetNS NetNS
+}
+
+// ifmap is the largest member of the ifreq union, it sets the union size
+// on every architecture
+type ifmap struct {
+	memStart uintptr
+	memEnd   uintptr
+	baseAddr uint16
+	irq      uint8
+	dma      uint8
+	port     uint8
+}
+
+const sizeofIfreqUnion = unsafe.Sizeof(ifmap{})
+
+// ifreqHwaddr is struct ifreq with ifr_hwaddr selected. sa_data extends
+// to the end of the union, a trailing zero length pad would make Go add
+// padding of its own on 32-bit architectures.
+type ifreqHwaddr struct {
+	name   [IFNAMSIZ]byte
+	family uint16
+	data   [sizeofIfreqUnion - 2]byte
+}
+
+// ifreqData is struct ifreq with ifr_data selected
+type ifreqData struct {
+	name [IFNAMSIZ]byte
+	data unsafe.Pointer
+	_    [sizeofIfreqUnion - unsafe.Sizeof(uintptr(0))]byte
+}
+
+// ifreqFlags is struct ifreq with ifr_flags selected
+type ifreqFlags struct {
+	name  [IFNAMSIZ]byte
+	flags uint16
+	_     [sizeofIfreqUnion - 2]byte
+}
+
+// EthtoolPermAddr is struct ethtool_perm_addr followed by room for the
+// largest hardware address the kernel supports
+type EthtoolPermAddr struct {
+	cmd  uint32
+	size uint32
+	data [MAX_ADDR_LEN]byte
+}
+
+func (b IoctlBackend) Links() (links []Link, err error) {
+	var ifaces []net.Interface
+	err = b.NetNS.Do(func() (err error) {
+		ifaces, err = net.Interfaces()
+		return
+	})
+	if err != nil {
+		return
+	}
+	sockfd, err := openIoctlSocket(b.NetNS)
+	if err != nil {
+		return
+	}
+	defer syscall.Close(sockfd)
+	for _, iface := range ifaces {
+		link := linkFromInterface(iface)
+		link.HardwareType, _ = getHardwareType(sockfd, link.Name)
+		links = append(links, link)
+	}
+	return
+}
+
+func (b IoctlBackend) LinkByName(name string) (link Link, err error) {
+	var iface *net.Interface
+	err = b.NetNS.Do(func() (err error) {
+		iface, err = net.InterfaceByName(name)
+		return
+	})
+	if err != nil {
+		return
+	}
+	link = linkFromInterface(*iface)
+	link.HardwareType, _ = b.hardwareType(link.Name)
+	return
+}
+
+func (b IoctlBackend) LinkByIndex(index int) (link Link, err error) {
+	var iface *net.Interface
+	err = b.NetNS.Do(func() (err error) {
+		iface, err = net.InterfaceByIndex(index)
+		return
+	})
+	if err != nil {
+		return
+	}
+	link = linkFromInterface(*iface)
+	link.HardwareType, _ = b.hardwareType(link.Name)
+	return
+}
+
+func (b IoctlBackend) SetHardwareAddr(link Link, mac net.HardwareAddr) (err error) {
+	sockfd, err := openIoctlSocket(b.NetNS)
+	if err != nil {
+		return
+	}
+	defer syscall.Close(sockfd)
+	// The kernel refuses addresses longer than sa_data, use netlink for them
+	if len(mac) > sizeofSockaddrData {
+		msg := fmt.Sprintf("Address too long for SIOCSIFHWADDR: %d bytes", len(mac))
+		err = UnsupportedAddressError{msg}
+		return
+	}
+	var ifr ifreqHwaddr
+	copy(ifr.name[:IFNAMSIZ-1], []byte(link.Name))
+	ifr.family = link.HardwareType
+	if ifr.family == 0 {
+		ifr.family = syscall.ARPHRD_ETHER
+	}
+	copy(ifr.data[:], []byte(mac))
+	err = ioctl(sockfd, SIOCSIFHWADDR, unsafe.Pointer(&ifr))
+	return
+}
+
+func (b IoctlBackend) SetAdminState(link Link, up bool) (err error) {
+	sockfd, err := openIoctlSocket(b.NetNS)
+	if err != nil {
+		return
+	}
+	defer syscall.Close(sockfd)
+	var ifr ifreqFlags
+	copy(ifr.name[:IFNAMSIZ-1], []byte(link.Name))
+	err = ioctl(sockfd, syscall.SIOCGIFFLAGS, unsafe.Pointer(&ifr))
+	if err != nil {
+		return
+	}
+	if up {
+		ifr.flags |= syscall.IFF_UP
+	} else {
+		ifr.flags &^= syscall.IFF_UP
+	}
+	err = ioctl(sockfd, syscall.SIOCSIFFLAGS, unsafe.Pointer(&ifr))
+	return
+}
+
+func (b IoctlBackend) hardwareType(name string) (hwType uint16, err error) {
+	sockfd, err := openIoctlSocket(b.NetNS)
+	if err != nil {
+		return
+	}
+	defer syscall.Close(sockfd)
+	return getHardwareType(sockfd, name)
+}
+
+// getHardwareType reads the ARPHRD type, SIOCGIFHWADDR returns it as the
+// address family
+func getHardwareType(sockfd int, name string) (hwType uint16, err error) {
+	var ifr ifreqHwaddr
+	copy(ifr.name[:IFNAMSIZ-1], []byte(name))
+	err = ioctl(sockfd, syscall.SIOCGIFHWADDR, unsafe.Pointer(&ifr))
+	if err != nil {
+		return
+	}
+	hwType = ifr.family
+	return
+}
+
+// openIoctlSocket returns a socket whose ioctls act on the interfaces of ns
+func openIoctlSocket(ns NetNS) (sockfd int, err error) {
+	err = ns.Do(func() (err error) {
+		sockfd, err = syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+		return
+	})
+	return
+}
+
+func ioctl(fd int, req uintptr, arg unsafe.Pointer) (err error) {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), req, uintptr(arg))
+	if errno != 0 {
+		err = syscall.Errno(errno)
+		return
+	}
+	return
+}
+
+func linkFromInterface(iface net.Interface) Link {
+	return Link{
+		Index:        iface.Index,
+		Name:         iface.Name,
+		MTU:          iface.MTU,
+		Flags:        iface.Flags,
+		HardwareAddr: iface.HardwareAddr,
+		Carrier:      iface.Flags&net.FlagRunning != 0,
+	}
+}
@@ -0,0 +1,55 @@
+package libmacouflage
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Sizes of struct ifreq as laid out by the kernel for 32-bit and 64-bit
+// architectures
+func expectedSizeofIfreq() uintptr {
+	if unsafe.Sizeof(uintptr(0)) == 8 {
+		return 40
+	}
+	return 32
+}
+
+func Test_IfreqLayout_1(t *testing.T) {
+	var ifr ifreqHwaddr
+	assert.Equal(t, expectedSizeofIfreq(), unsafe.Sizeof(ifr))
+	assert.Equal(t, uintptr(IFNAMSIZ), unsafe.Offsetof(ifr.family))
+	assert.Equal(t, uintptr(IFNAMSIZ+2), unsafe.Offsetof(ifr.data))
+}
+
+func Test_IfreqLayout_2(t *testing.T) {
+	var ifr ifreqData
+	assert.Equal(t, expectedSizeofIfreq(), unsafe.Sizeof(ifr))
+	assert.Equal(t, uintptr(IFNAMSIZ), unsafe.Offsetof(ifr.data))
+}
+
+func Test_IfreqLayout_3(t *testing.T) {
+	var ifr ifreqFlags
+	assert.Equal(t, expectedSizeofIfreq(), unsafe.Sizeof(ifr))
+	assert.Equal(t, uintptr(IFNAMSIZ), unsafe.Offsetof(ifr.flags))
+}
+
+func Test_EthtoolPermAddrLayout_1(t *testing.T) {
+	var epa EthtoolPermAddr
+	assert.Equal(t, uintptr(4), unsafe.Offsetof(epa.size))
+	assert.Equal(t, uintptr(8), unsafe.Offsetof(epa.data))
+	assert.Equal(t, uintptr(8+MAX_ADDR_LEN), unsafe.Sizeof(epa))
+}
+
+func Test_GetEthtoolPermanentMac_1(t *testing.T) {
+	_, err := getEthtoolPermanentMac(NetNS{}, "badinterface")
+	assert.Error(t, err, "Function failed to generate error for bad interface")
+}
+
+func Test_EthtoolDrvinfoLayout_1(t *testing.T) {
+	var drvinfo EthtoolDrvinfo
+	assert.Equal(t, uintptr(4), unsafe.Offsetof(drvinfo.driver))
+	assert.Equal(t, uintptr(176), unsafe.Offsetof(drvinfo.nPrivFlags))
+	assert.Equal(t, uintptr(196), unsafe.Sizeof(drvinfo))
+}
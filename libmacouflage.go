@@ -1,53 +1,32 @@
 package libmacouflage
 
-import "C"
 import (
-	"syscall"
+	"context"
+	"errors"
 	"fmt"
 	"net"
-	"unsafe"
-	rand "crypto/rand"
 	"os/user"
 	"encoding/json"
 	"strings"
-	mathrand "math/rand"
-	"time"
-	"regexp"
+	"syscall"
 )
 
 const SIOCSIFHWADDR = 0x8924
 const SIOCETHTOOL = 0x8946
 const ETHTOOL_GPERMADDR = 0x00000020
 const IFHWADDRLEN = 6
+const IFNAMSIZ = 16
+const MAX_ADDR_LEN = 32
+// OuiDb is indexed on the first lookup. Replacing it is noticed, but entries
+// changed in place are not until ReindexOuiDb is called.
 var OuiDb []Oui
 
-const (
-	invalidInterfaceRegexp = "^(lo|br|veth|tun|tap|oz|voz).*$"
-)
-
 type Mode struct {
 	name string
 	help string
 	flagShort string
 	flagLong string
-}
-
-// TODO: Ad-hoc structs that work, fix
-type NetInfo struct {
-	name [16]byte
-	family uint16
-	data [6]byte
-}
-
-type ifreq struct {
-	name [16]byte
-        epa *EthtoolPermAddr
-}
-
-type EthtoolPermAddr struct {
-	cmd uint32
-	size uint32
-	data [6]byte
+	generate generateFunc
 }
 
 type Oui struct {
@@ -55,6 +34,8 @@ type Oui struct {
 	Popular bool		`json:"is_popular"`
 	Vendor string		`json:"vendor_name"`
 	Devices []Device	`json:"devices"`
+	// Assignment is the kind of IEEE assignment, see AssignmentType
+	Assignment string	`json:"assignment,omitempty"`
 }
 
 type Device struct {
@@ -64,34 +45,28 @@ type Device struct {
 
 type NoVendorError struct {
 	msg string
+	Prefix string
 }
 
 type InvalidInterfaceTypeError struct {
 	msg string
+	Interface string
+	Kind InterfaceKind
 }
 
 func init() {
-	Modes := make(map[string]Mode)
-	Modes["SPECIFIC"] = Mode{"Specific",
-		"Set the MAC XX:XX:XX:XX:XX:XX",
-		"m",
-		"mac"}
-	Modes["RANDOM"] = Mode{"Random",
-		"Set fully random MAC",
-		"r",
-		"random"}
-	Modes["SAMEVENDOR"] = Mode{"Same Vendor",
-		"Don't change the vendor bytes",
-		"e",
-		"ending"}
-	Modes["ANOTHER"] = Mode{"Another",
-		"Set random vendor MAC of the same kind",
-		"a",
-		"another"}
-	Modes["ANY"] = Mode{"Any",
-		"Set random vendor MAC of any kind",
-        "A",
-		"any"}
+	for _, mode := range []Mode{
+		NewMode("Specific", "Set the MAC XX:XX:XX:XX:XX:XX", "m", ModeSpecific, nil),
+		RandomStrategy(false).(Mode),
+		SameVendorStrategy(true).(Mode),
+		NewMode("Another", "Set random vendor MAC of the same kind", "a", ModeAnother, generateSameDeviceType),
+		NewMode("Any", "Set random vendor MAC of any kind", "A", ModeAny, generateAnyDeviceType),
+		NewMode("Popular", "Set random MAC of a popular vendor", "", ModePopular, generatePopular),
+		SLAPStrategy(SLAPAAI).(Mode),
+		SLAPStrategy(SLAPSAI).(Mode),
+	} {
+		RegisterStrategy(mode)
+	}
 
 	OuiData, err := Asset("data/ouis.json")
 	if err != nil {
@@ -106,21 +81,24 @@ func init() {
 }
 
 func GetCurrentMac(name string) (mac net.HardwareAddr, err error) {
-	if IsInterfaceTypeInvalid(name) {
-		msg := fmt.Sprintf("Invalid interface type: %s", name)
-		err = InvalidInterfaceTypeError{msg}
-		return
-	}
-	iface, err := net.InterfaceByName(name)
+	return defaultHandle().GetCurrentMac(name)
+}
+
+func (h *Handle) GetCurrentMac(name string) (mac net.HardwareAddr, err error) {
+	link, err := h.checkInterface(name)
 	if err != nil {
 		return
 	}
-	mac = iface.HardwareAddr
+	mac = link.HardwareAddr
 	return
 }
 
 func GetAllCurrentMacs() (macs map[string]string, err error) {
-	ifaces, err := GetInterfaces()
+	return defaultHandle().GetAllCurrentMacs()
+}
+
+func (h *Handle) GetAllCurrentMacs() (macs map[string]string, err error) {
+	ifaces, err := h.GetInterfaces()
 	macs = make(map[string]string)
 	for _, iface := range ifaces {
 		macs[iface.Name] = iface.HardwareAddr.String()
@@ -129,219 +107,264 @@ func GetAllCurrentMacs() (macs map[string]string, err error) {
 }
 
 func GetInterfaces() (ifaces []net.Interface, err error) {
-	allIfaces, err := net.Interfaces()
-	for _, iface := range allIfaces {
-		// Skip invalid interfaces
-		if IsInterfaceTypeInvalid(iface.Name) {
+	return defaultHandle().GetInterfaces()
+}
+
+func (h *Handle) GetInterfaces() (ifaces []net.Interface, err error) {
+	links, err := h.backend.Links()
+	err = linkError("list", "", err)
+	for _, link := range links {
+		// Skip interfaces the policy refuses
+		if _, allowed := h.interfaceAllowed(link); !allowed {
 			continue
 		}
-		ifaces = append(ifaces, iface)
+		ifaces = append(ifaces, link.Interface())
 	}
 	return
 }
 
 func GetPermanentMac(name string) (mac net.HardwareAddr, err error) {
-	if IsInterfaceTypeInvalid(name) {
-		msg := fmt.Sprintf("Invalid interface type: %s", name)
-		err = InvalidInterfaceTypeError{msg}
-		return
-	}
-	sockfd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
-	defer syscall.Close(sockfd)
-	var ifr ifreq
-	copy(ifr.name[:], []byte(name))
-	var epa EthtoolPermAddr
-	epa.cmd = ETHTOOL_GPERMADDR
-	epa.size = IFHWADDRLEN
-	ifr.epa = &epa
-	_, _, errno  := syscall.Syscall(syscall.SYS_IOCTL, uintptr(sockfd), SIOCETHTOOL, uintptr(unsafe.Pointer(&ifr)))
-	if errno != 0 {
-		err = syscall.Errno(errno)
+	return defaultHandle().GetPermanentMac(name)
+}
+
+func (h *Handle) GetPermanentMac(name string) (mac net.HardwareAddr, err error) {
+	perm, err := h.GetPermanentMacInfo(name)
+	if err != nil {
 		return
 	}
-	mac = net.HardwareAddr(C.GoBytes(unsafe.Pointer(&ifr.epa.data), 6))
+	mac = perm.HardwareAddr
 	return
 }
 
 func GetAllPermanentMacs() (macs map[string]string, err error) {
-	ifaces, err := GetInterfaces()
-	for _, iface := range ifaces {
-		name, err := GetPermanentMac(iface.Name)
-		if err != nil {
-			fmt.Println(err)
-		}
-		macs[name.String()] = iface.HardwareAddr.String()
-	}
-	return
+	return defaultHandle().GetAllPermanentMacs()
 }
 
-func SetMac(name string, mac string) (err error) {
-	if IsInterfaceTypeInvalid(name) {
-		msg := fmt.Sprintf("Invalid interface type: %s", name)
-		err = InvalidInterfaceTypeError{msg}
-		return
-	}
-	result, err := RunningAsRoot()
-	if err != nil {
-		return
-	}
-	if !result {
-		err = fmt.Errorf("Not running as root, insufficient privileges to set MAC on %s",
-		name)
-		return
-	}
-	result, err = IsIfUp(name)
+// GetAllPermanentMacs maps the names of the interfaces to their permanent
+// MACs. Interfaces whose permanent MAC cannot be found are left out and
+// their errors joined.
+func (h *Handle) GetAllPermanentMacs() (macs map[string]string, err error) {
+	ifaces, err := h.GetInterfaces()
 	if err != nil {
 		return
 	}
-	if result {
-		err = fmt.Errorf("%s interface is still up, cannot set MAC", name)
-		return
-	}
-	sockfd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
-	defer syscall.Close(sockfd)
-	iface, err := net.ParseMAC(mac)
-	if err != nil {
-		return
-	}
-	var netinfo NetInfo
-	copy(netinfo.name[:], []byte(name))
-	netinfo.family = syscall.AF_UNIX
-	copy(netinfo.data[:], []byte(iface))
-	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(sockfd), SIOCSIFHWADDR, uintptr(unsafe.Pointer(&netinfo)))
-	if errno != 0 {
-		err = syscall.Errno(errno)
-		return
+	macs = make(map[string]string)
+	var errs []error
+	for _, iface := range ifaces {
+		mac, perr := h.GetPermanentMac(iface.Name)
+		if perr != nil {
+			errs = append(errs, perr)
+			continue
+		}
+		macs[iface.Name] = mac.String()
 	}
+	err = errors.Join(errs...)
 	return
 }
 
-func SpoofMacRandom(name string, bia bool) (changed bool, err error) {
-	bytes := []byte{0, 0, 0, 0, 0, 0}
-	mac, err := RandomizeMac(bytes, 0, bia)
-	if err != nil {
-		return
-	}
-	err = SetMac(name, mac.String())
-	if err != nil {
-		return
-	}
-	changed, err = MacChanged(name)
-	if err != nil {
-		return
-	}
+func SetMac(name string, mac string) (err error) {
+	return defaultHandle().SetMac(name, mac)
+}
+
+func (h *Handle) SetMac(name string, mac string) (err error) {
+	_, err = h.SetMacWithOptions(name, mac, SetMacOptions{})
 	return
 }
 
+func SetMacWithOptions(name string, mac string, opts SetMacOptions) (result SetMacResult, err error) {
+	return defaultHandle().SetMacWithOptions(name, mac, opts)
+}
 
-func SpoofMacSameVendor(name string, bia bool) (changed bool, err error) {
-	oldMac, err := GetCurrentMac(name)
-	if err != nil {
-		return
-	}
-	mac, err := RandomizeMac(oldMac, 3, bia)
-	if err != nil {
-		return
-	}
-	err = SetMac(name, mac.String())
-	if err != nil {
-		return
-	}
-	changed, err = MacChanged(name)
-	if err != nil {
-		return
-	}
-	return
+func (h *Handle) SetMacWithOptions(name string, mac string, opts SetMacOptions) (result SetMacResult, err error) {
+	return h.setMacWithOptions(context.Background(), name, mac, opts)
 }
 
-func SpoofMacSameDeviceType(name string) (changed bool, err error) {
-	oldMac, err := GetCurrentMac(name)
+// setMacWithOptions gives up when ctx is done, until the link is down. The
+// change is seen through from there so that the link is not left down.
+func (h *Handle) setMacWithOptions(ctx context.Context, name string, mac string, opts SetMacOptions) (result SetMacResult, err error) {
+	result.Interface = name
+	_, err = h.checkInterface(name)
 	if err != nil {
 		return
 	}
-	deviceType, err := FindDeviceTypeByMac(oldMac.String())
+	err = h.checkNetAdmin(name)
 	if err != nil {
 		return
 	}
-	vendors, err := FindAllVendorsByDeviceType(deviceType)
+	hwaddr, err := net.ParseMAC(mac)
 	if err != nil {
+		err = InvalidMacError{mac, err}
 		return
 	}
-	mathrand.Seed(time.Now().UTC().UnixNano())
-	vendor := vendors[mathrand.Intn(len(vendors))]
-	macBytes, err := net.ParseMAC(vendor.VendorPrefix + ":00:00:00")
+	link, err := h.lookup(name)
 	if err != nil {
 		return
 	}
-	newMac, err := RandomizeMac(macBytes, 3, true)
+	result.OldMac = link.HardwareAddr
+	result.WasUp = link.IsUp()
+	format, err := AddressFormatOf(link)
 	if err != nil {
 		return
 	}
-	err = SetMac(name, newMac.String())
+	err = format.Check(hwaddr)
 	if err != nil {
 		return
 	}
-	changed, err = MacChanged(name)
-	if err != nil {
+	if err = ctx.Err(); err != nil {
 		return
 	}
-	return
-}
-
-func SpoofMacAnyDeviceType(name string) (changed bool, err error) {
-	vendor := OuiDb[RandomInt(len(OuiDb))]
-	macBytes, err := net.ParseMAC(vendor.VendorPrefix + ":00:00:00")
-	if err != nil {
-		return
-	}
-	newMac, err := RandomizeMac(macBytes, 3, true)
-	if err != nil {
-		return
-	}
-	err = SetMac(name, newMac.String())
-	if err != nil {
-		return
+	var netConfigBackend NetConfigBackend
+	if opts.PreserveNetConfig {
+		var ok bool
+		netConfigBackend, ok = h.backend.(NetConfigBackend)
+		if !ok {
+			err = LinkError{ErrNotSupported, "snapshot", name, nil}
+			return
+		}
+		err = result.runPhase(PhaseSnapshot, func() (err error) {
+			result.NetConfig, err = netConfigBackend.SnapshotNetConfig(link)
+			return
+		})
+		if err != nil {
+			err = linkError("snapshot", name, err)
+			return
+		}
 	}
-	changed, err = MacChanged(name)
-	if err != nil {
-		return
+	h.recordOriginalMac(link)
+	var liveErr error
+	if result.WasUp && !opts.NoLiveChange {
+		result.Driver = h.driverCapabilities(link)
+		if result.Driver.LiveAddrChange != LiveChangeUnsupported {
+			err = result.runPhase(PhaseLive, func() error {
+				return h.backend.SetHardwareAddr(link, hwaddr)
+			})
+			if err == nil {
+				result.NewMac = hwaddr
+				result.LiveChange = true
+				return
+			}
+			// Drivers without IFF_LIVE_ADDR_CHANGE refuse with EBUSY
+			if !errors.Is(err, syscall.EBUSY) {
+				err = setAddressError(name, hwaddr, err)
+				return
+			}
+			liveErr, err = err, nil
+		}
 	}
-	return
-}
-
-func SpoofMacPopular(name string) (changed bool, err error) {
-	popular, err := FindAllPopularOuis()
-	if err != nil {
-		return
+	cycled := false
+	if result.WasUp {
+		if !opts.AutoDown {
+			err = LinkError{ErrInterfaceUp, "set", name, liveErr}
+			return
+		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		err = result.runPhase(PhaseDown, func() error {
+			return h.backend.SetAdminState(link, false)
+		})
+		if err != nil {
+			err = linkError("down", name, err)
+			return
+		}
+		cycled = true
+	}
+	// The link is addressed by index from here on, a concurrent rename
+	// cannot redirect the change to another interface
+	err = result.runPhase(PhaseSet, func() error {
+		return h.backend.SetHardwareAddr(link, hwaddr)
+	})
+	if err == nil {
+		result.NewMac = hwaddr
+	}
+	err = setAddressError(name, hwaddr, err)
+	if cycled {
+		// Restore the admin state even when the change failed
+		upErr := result.runPhase(PhaseUp, func() error {
+			return h.backend.SetAdminState(link, true)
+		})
+		if err == nil {
+			err = linkError("up", name, upErr)
+		}
 	}
-	vendor := popular[RandomInt(len(popular))]
-	macBytes, err := net.ParseMAC(vendor.VendorPrefix + ":00:00:00")
-	newMac, err := RandomizeMac(macBytes, 3, true)
-	if err != nil {
-		return
+	if netConfigBackend != nil {
+		// Entries that did not make it back are reported, not fatal
+		result.runPhase(PhaseRestore, func() error {
+			result.RestoreFailures = netConfigBackend.RestoreNetConfig(link, result.NetConfig)
+			if len(result.RestoreFailures) > 0 {
+				return fmt.Errorf("%d of %d network configuration entries not restored",
+					len(result.RestoreFailures), len(result.NetConfig.Entries))
+			}
+			return nil
+		})
 	}
-	err = SetMac(name, newMac.String())
-	if err != nil {
+	if err != nil || !cycled {
 		return
 	}
-	changed, err = MacChanged(name)
-	if err != nil {
-		return
+	// A link that is slow to come back is reported, the MAC is set anyway
+	if opts.LinkTimeout > 0 {
+		result.runPhase(PhaseWait, func() error {
+			return h.waitForLink(ctx, link.Index, opts.LinkTimeout, &result)
+		})
 	}
 	return
 }
 
+func SpoofMacRandom(name string, bia bool) (result SpoofResult, err error) {
+	return defaultHandle().SpoofMacRandom(name, bia)
+}
+
+func (h *Handle) SpoofMacRandom(name string, bia bool) (result SpoofResult, err error) {
+	return h.spoofMode(name, ModeRandom, WithBIA(bia))
+}
+
+func SpoofMacSameVendor(name string, bia bool) (result SpoofResult, err error) {
+	return defaultHandle().SpoofMacSameVendor(name, bia)
+}
+
+func (h *Handle) SpoofMacSameVendor(name string, bia bool) (result SpoofResult, err error) {
+	return h.spoofMode(name, ModeEnding, WithBIA(bia))
+}
+
+func SpoofMacSameDeviceType(name string) (result SpoofResult, err error) {
+	return defaultHandle().SpoofMacSameDeviceType(name)
+}
+
+func (h *Handle) SpoofMacSameDeviceType(name string) (result SpoofResult, err error) {
+	return h.spoofMode(name, ModeAnother)
+}
+
+func SpoofMacAnyDeviceType(name string) (result SpoofResult, err error) {
+	return defaultHandle().SpoofMacAnyDeviceType(name)
+}
+
+func (h *Handle) SpoofMacAnyDeviceType(name string) (result SpoofResult, err error) {
+	return h.spoofMode(name, ModeAny)
+}
+
+func SpoofMacPopular(name string) (result SpoofResult, err error) {
+	return defaultHandle().SpoofMacPopular(name)
+}
+
+func (h *Handle) SpoofMacPopular(name string) (result SpoofResult, err error) {
+	return h.spoofMode(name, ModePopular)
+}
+
 func CompareMacs(first net.HardwareAddr, second net.HardwareAddr) (same bool) {
 	same = first.String() == second.String()
 	return
 }
 
 func MacChanged(iface string) (changed bool, err error) {
-	current, err := GetCurrentMac(iface)
+	return defaultHandle().MacChanged(iface)
+}
+
+func (h *Handle) MacChanged(iface string) (changed bool, err error) {
+	current, err := h.GetCurrentMac(iface)
 	if err != nil {
 		return
 	}
-	permanent, err := GetPermanentMac(iface)
+	permanent, err := h.GetPermanentMac(iface)
 	if err != nil {
 		return
 	}
@@ -352,71 +375,62 @@ func MacChanged(iface string) (changed bool, err error) {
 }
 
 func IsIfUp(name string) (result bool, err error) {
-	iface, err := net.InterfaceByName(name)
+	return defaultHandle().IsIfUp(name)
+}
+
+func (h *Handle) IsIfUp(name string) (result bool, err error) {
+	link, err := h.lookup(name)
 	if err != nil {
 		return
 	}
-        if iface.Flags&net.FlagUp != 0 {
-		result = true
-	}
+	result = link.IsUp()
 	return
 }
 
 func RevertMac(name string) (err error) {
-	_, err = net.InterfaceByName(name)
+	return defaultHandle().RevertMac(name)
+}
+
+func (h *Handle) RevertMac(name string) (err error) {
+	_, err = h.lookup(name)
 	if err != nil {
 		return
 	}
-	mac, err := GetPermanentMac(name)
+	mac, err := h.GetPermanentMac(name)
 	if err != nil {
 		return
 	}
-	err = SetMac(name, mac.String())
+	err = h.SetMac(name, mac.String())
 	return
 }
 
+// RandomizeMac randomizes an EUI-48 or EUI-64 in place from byte start on,
+// 0 for the whole address or 3 to keep the OUI. RandomizeMacPrefix keeps
+// prefixes of any length.
 func RandomizeMac(macbytes net.HardwareAddr, start int, bia bool) (mac net.HardwareAddr, err error) {
-	if len(macbytes) != 6 {
-		err = fmt.Errorf("Invalid size for macbytes byte array: %d", 
-		len(macbytes))
+	if len(macbytes) != 6 && len(macbytes) != 8 {
+		err = InvalidMacError{macbytes.String(), fmt.Errorf("Invalid size for macbytes byte array: %d", 
+		len(macbytes))}
 		return
 	}
 	if (start != 0 && start != 3) {
-		err = fmt.Errorf("Invalid start index: %d", start) 
+		err = fmt.Errorf("%w: invalid start index %d", ErrInvalidArgument, start) 
 		return
 	}
-	for i := start; i < 6; i++ {
-		buf := make([]byte, 1)
-		rand.Read(buf)
-
-		/* The LSB of first octet can not be set.  Those are musticast
-         * MAC addresses and not allowed for network device:
-         * x1:, x3:, x5:, x7:, x9:, xB:, xD: and xF:
-         */
-		if i == 0 {
-			macbytes[i] = buf[0] & 0xfc
-		} else {
-			macbytes[i] = buf[0]
-		}
-	}
-	if bia {
-		macbytes[0] = macbytes[0]&^2
-	} else {
-		macbytes[0] |= 2
-	}
-	mac = macbytes
-	return
+	return RandomizeMacPrefix(macbytes, start*8, bia)
 }
 
+// Deprecated: root is neither needed nor sufficient in another user
+// namespace, use HasNetAdmin.
 func RunningAsRoot() (result bool, err error) {
 	current, err := user.Current()
 	if err != nil {
-		fmt.Println(err)
+		return
 	}
 	if current.Uid == "0" && current.Gid == "0" && current.Username == "root" {
 		result = true
 	}
-	return 
+	return
 }
 
 func FindAllPopularOuis() (matches []Oui, err error) {
@@ -436,19 +450,18 @@ func (e NoVendorError) Error() string {
 	return e.msg
 }
 
+// FindVendorByMac returns the entry of OuiDb with the longest prefix of mac.
 func FindVendorByMac(mac string) (vendor Oui, err error) {
 	err = ValidateMac(mac)
 	if err != nil {
 		return
 	}
-	for _, oui := range OuiDb {
-		if(strings.EqualFold(oui.VendorPrefix, mac[:8])) {
-			vendor = oui
-			return
-		}
+	addr, _ := net.ParseMAC(mac)
+	vendor, ok := findOui(addr)
+	if !ok {
+		msg := fmt.Sprintf("No vendor found in OuiDb for MAC: %s", mac)
+		err = NoVendorError{msg, mac}
 	}
-	msg := fmt.Sprintf("No vendor found in OuiDb for vendor prefix: %s", mac[:8])
-	err = NoVendorError{msg}
 	return
 }
 
@@ -457,11 +470,10 @@ func FindDeviceTypeByMac(mac string) (deviceType string, err error) {
 	if err != nil {
 		return
 	}
-	for _, oui := range OuiDb {
-		if(strings.EqualFold(oui.VendorPrefix, mac[:8])) {
-			deviceType = oui.Devices[0].DeviceType
-			return
-		}
+	addr, _ := net.ParseMAC(mac)
+	if oui, ok := findOui(addr); ok && len(oui.Devices) > 0 {
+		deviceType = oui.Devices[0].DeviceType
+		return
 	}
 	// If vendor prefix is not in OuiDb, return type "Other"
 	deviceType = "Other"
@@ -470,7 +482,7 @@ func FindDeviceTypeByMac(mac string) (deviceType string, err error) {
 
 func FindAllVendorsByDeviceType(deviceType string) (matches []Oui, err error) {
 	for _, oui := range OuiDb {
-		if(strings.EqualFold(deviceType, oui.Devices[0].DeviceType)) {
+		if(len(oui.Devices) > 0 && strings.EqualFold(deviceType, oui.Devices[0].DeviceType)) {
 			matches = append(matches, oui)
 		}
 	}
@@ -488,17 +500,21 @@ func FindVendorsByKeyword(keyword string) (matches []Oui, err error) {
 
 func ValidateMac(mac string) (err error) {
 	_, err = net.ParseMAC(mac)
+	if err != nil {
+		err = InvalidMacError{mac, err}
+	}
 	return
 }
 
+// IsInterfaceTypeInvalid reports whether the interface policy refuses name.
+// Interfaces that cannot be looked up or classified are refused too.
 func IsInterfaceTypeInvalid(name string) (result bool) {
-	result = regexp.MustCompile(invalidInterfaceRegexp).MatchString(name)
-	return
+	return defaultHandle().IsInterfaceTypeInvalid(name)
 }
 
-func RandomInt(max int) (result int) {
-	mathrand.Seed(time.Now().UTC().UnixNano())
-	result = mathrand.Intn(max)
+func (h *Handle) IsInterfaceTypeInvalid(name string) (result bool) {
+	_, err := h.checkInterface(name)
+	result = err != nil
 	return
 }
 
@@ -0,0 +1,148 @@
+package libmacouflage
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// OperState is the RFC 2863 operational state of a link, IF_OPER_* in the
+// kernel.
+type OperState uint8
+
+const (
+	OperUnknown OperState = iota
+	OperNotPresent
+	OperDown
+	OperLowerLayerDown
+	OperTesting
+	OperDormant
+	OperUp
+)
+
+const linkPollInterval = 100 * time.Millisecond
+
+type SetMacPhase string
+
+const (
+	PhaseDown SetMacPhase = "down"
+	PhaseSet  SetMacPhase = "set"
+	PhaseUp   SetMacPhase = "up"
+	PhaseWait SetMacPhase = "wait"
+	PhaseLive SetMacPhase = "live"
+
+	PhaseSnapshot SetMacPhase = "snapshot"
+	PhaseRestore  SetMacPhase = "restore"
+)
+
+type SetMacOptions struct {
+	// AutoDown brings an up link down for the change and restores its
+	// admin state afterwards, instead of refusing to touch it.
+	AutoDown bool
+	// LinkTimeout bounds the wait for a restored link to report that it
+	// is operational again. Zero skips the wait.
+	LinkTimeout time.Duration
+	// NoLiveChange skips changing the address of an up link in place, even
+	// when its driver allows it.
+	NoLiveChange bool
+	// PreserveNetConfig saves the addresses, routes and proxy neighbors of
+	// the interface before the change and reinstalls them afterwards.
+	PreserveNetConfig bool
+}
+
+type PhaseReport struct {
+	Phase    SetMacPhase
+	Duration time.Duration
+	Err      error
+}
+
+type SetMacResult struct {
+	Interface string
+	OldMac    net.HardwareAddr
+	NewMac    net.HardwareAddr
+	WasUp     bool
+	// LiveChange is set when the address changed without taking the link
+	// down
+	LiveChange bool
+	Driver     DriverCapabilities
+	Phases     []PhaseReport
+	// The state observed at the end of the wait phase
+	OperState OperState
+	Carrier   bool
+	LinkReady bool
+	// Filled in when PreserveNetConfig was requested
+	NetConfig       NetConfig
+	RestoreFailures []RestoreFailure
+}
+
+func (s OperState) String() string {
+	switch s {
+	case OperNotPresent:
+		return "notpresent"
+	case OperDown:
+		return "down"
+	case OperLowerLayerDown:
+		return "lowerlayerdown"
+	case OperTesting:
+		return "testing"
+	case OperDormant:
+		return "dormant"
+	case OperUp:
+		return "up"
+	}
+	return "unknown"
+}
+
+// IsReady reports whether the link can pass traffic. Many virtual drivers
+// never leave the unknown state, for those the carrier decides.
+func (l Link) IsReady() bool {
+	return l.OperState == OperUp || (l.OperState == OperUnknown && l.Carrier)
+}
+
+func (r *SetMacResult) runPhase(phase SetMacPhase, f func() error) (err error) {
+	start := time.Now()
+	err = f()
+	r.Phases = append(r.Phases, PhaseReport{phase, time.Since(start), err})
+	return
+}
+
+// Phase returns the report for phase, if it ran.
+func (r SetMacResult) Phase(phase SetMacPhase) (report PhaseReport, ok bool) {
+	for _, report = range r.Phases {
+		if report.Phase == phase {
+			ok = true
+			return
+		}
+	}
+	report = PhaseReport{}
+	return
+}
+
+func (h *Handle) waitForLink(ctx context.Context, index int, timeout time.Duration, result *SetMacResult) (err error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		link, lerr := h.backend.LinkByIndex(index)
+		if lerr != nil {
+			err = linkError("wait", result.Interface, lerr)
+			return
+		}
+		result.OperState = link.OperState
+		result.Carrier = link.Carrier
+		if link.IsReady() {
+			result.LinkReady = true
+			return
+		}
+		if !time.Now().Before(deadline) {
+			cause := fmt.Errorf("no carrier within %s (operstate %s)", timeout, link.OperState)
+			err = LinkError{ErrLinkNotReady, "wait", link.Name, cause}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			err = LinkError{ErrLinkNotReady, "wait", link.Name, ctx.Err()}
+			return
+		case <-time.After(linkPollInterval):
+		}
+	}
+}
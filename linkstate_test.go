@@ -0,0 +1,152 @@
+package libmacouflage
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingBackend struct {
+	link       Link
+	calls      []string
+	failSet    bool
+	busyWhenUp bool
+}
+
+func (b *recordingBackend) Links() ([]Link, error) {
+	return []Link{b.link}, nil
+}
+
+func (b *recordingBackend) LinkByName(name string) (Link, error) {
+	return b.link, nil
+}
+
+func (b *recordingBackend) LinkByIndex(index int) (Link, error) {
+	return b.link, nil
+}
+
+func (b *recordingBackend) SetHardwareAddr(link Link, mac net.HardwareAddr) error {
+	b.calls = append(b.calls, "set")
+	if b.busyWhenUp && b.link.IsUp() {
+		return NetlinkError{Errno: syscall.EBUSY}
+	}
+	if b.failSet {
+		return fmt.Errorf("address rejected")
+	}
+	b.link.HardwareAddr = mac
+	return nil
+}
+
+func (b *recordingBackend) SetAdminState(link Link, up bool) error {
+	if up {
+		b.calls = append(b.calls, "up")
+		b.link.Flags |= net.FlagUp
+		b.link.OperState = OperUp
+	} else {
+		b.calls = append(b.calls, "down")
+		b.link.Flags &^= net.FlagUp
+		b.link.OperState = OperDown
+	}
+	return nil
+}
+
+func newRecordingBackend(name string) *recordingBackend {
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+	return &recordingBackend{link: Link{
+		Index:        2000,
+		Name:         name,
+		Flags:        net.FlagUp,
+		HardwareAddr: mac,
+		OperState:    OperUp,
+	}}
+}
+
+func Test_SetMacWithOptions_1(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	h := NewHandle(backend)
+	_, err := h.SetMacWithOptions("rec0", "00:11:22:00:00:01", SetMacOptions{NoLiveChange: true})
+	assert.Error(t, err, "Function failed to generate error for up interface")
+	assert.Empty(t, backend.calls)
+}
+
+func Test_SetMacWithOptions_2(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	backend.busyWhenUp = true
+	h := NewHandle(backend)
+	result, err := h.SetMacWithOptions("rec0", "00:11:22:00:00:01",
+		SetMacOptions{AutoDown: true, LinkTimeout: time.Second})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"set", "down", "set", "up"}, backend.calls)
+	assert.True(t, result.WasUp)
+	assert.False(t, result.LiveChange)
+	assert.True(t, result.LinkReady)
+	assert.Equal(t, "00:11:22:00:00:01", result.NewMac.String())
+	assert.Equal(t, "00:11:22:33:44:55", result.OldMac.String())
+	_, ok := result.Phase(PhaseWait)
+	assert.True(t, ok)
+}
+
+func Test_SetMacWithOptions_3(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	backend.failSet = true
+	h := NewHandle(backend)
+	result, err := h.SetMacWithOptions("rec0", "00:11:22:00:00:01",
+		SetMacOptions{AutoDown: true, NoLiveChange: true})
+	assert.Error(t, err, "Function failed to report rejected address")
+	assert.Equal(t, []string{"down", "set", "up"}, backend.calls)
+	assert.True(t, backend.link.IsUp(), "Admin state was not restored")
+	report, ok := result.Phase(PhaseSet)
+	assert.True(t, ok)
+	assert.Error(t, report.Err)
+	assert.Nil(t, result.NewMac)
+}
+
+func Test_SetMacWithOptions_4(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	backend.link.Flags = 0
+	h := NewHandle(backend)
+	result, err := h.SetMacWithOptions("rec0", "00:11:22:00:00:01", SetMacOptions{AutoDown: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"set"}, backend.calls)
+	assert.False(t, result.WasUp)
+}
+
+func Test_SetMacWithOptions_5(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	h := NewHandle(backend)
+	result, err := h.SetMacWithOptions("rec0", "00:11:22:00:00:01", SetMacOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"set"}, backend.calls)
+	assert.True(t, result.LiveChange)
+	assert.True(t, backend.link.IsUp())
+	_, ok := result.Phase(PhaseLive)
+	assert.True(t, ok)
+}
+
+func Test_SetMacWithOptions_6(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	backend.busyWhenUp = true
+	h := NewHandle(backend)
+	_, err := h.SetMacWithOptions("rec0", "00:11:22:00:00:01", SetMacOptions{})
+	assert.Error(t, err, "Function failed to generate error for busy interface")
+	assert.Equal(t, []string{"set"}, backend.calls)
+}
+
+func Test_SetMacWithOptions_7(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	backend.failSet = true
+	h := NewHandle(backend)
+	_, err := h.SetMacWithOptions("rec0", "00:11:22:00:00:01", SetMacOptions{AutoDown: true})
+	assert.Error(t, err, "Function failed to report rejected address")
+	assert.Equal(t, []string{"set"}, backend.calls, "Link was cycled for a non-busy error")
+}
+
+func Test_Link_IsReady_1(t *testing.T) {
+	assert.True(t, Link{OperState: OperUp}.IsReady())
+	assert.True(t, Link{OperState: OperUnknown, Carrier: true}.IsReady())
+	assert.False(t, Link{OperState: OperLowerLayerDown, Carrier: true}.IsReady())
+}
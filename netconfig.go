@@ -0,0 +1,233 @@
+package libmacouflage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+const (
+	NTF_PROXY = 0x08
+	NDA_DST   = 1
+
+	sizeofNdMsg = 12
+
+	NLA_TYPE_MASK = 0x3fff
+)
+
+type NetConfigKind string
+
+const (
+	NetConfigAddress       NetConfigKind = "address"
+	NetConfigRoute         NetConfigKind = "route"
+	NetConfigNeighborProxy NetConfigKind = "neighbor-proxy"
+)
+
+// NetConfigEntry is one address, route or proxy neighbor of an interface,
+// kept as the netlink message that reinstalls it.
+type NetConfigEntry struct {
+	Kind        NetConfigKind
+	Description string
+	msgType     uint16
+	data        []byte
+}
+
+// NetConfig is the network configuration attached to one interface.
+type NetConfig struct {
+	Index   int
+	Entries []NetConfigEntry
+}
+
+type RestoreFailure struct {
+	Entry NetConfigEntry
+	Err   error
+}
+
+// NetConfigBackend is implemented by backends that can save and reinstall
+// the configuration the kernel drops when a link goes down.
+type NetConfigBackend interface {
+	SnapshotNetConfig(link Link) (NetConfig, error)
+	RestoreNetConfig(link Link, config NetConfig) []RestoreFailure
+}
+
+func (e NetConfigEntry) String() string {
+	return fmt.Sprintf("%s %s", e.Kind, e.Description)
+}
+
+func (f RestoreFailure) Error() string {
+	return fmt.Sprintf("Cannot restore %s: %v", f.Entry, f.Err)
+}
+
+func (b NetlinkBackend) SnapshotNetConfig(link Link) (config NetConfig, err error) {
+	s, err := b.open()
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	config.Index = link.Index
+
+	req := newNetlinkRequest(syscall.RTM_GETADDR, syscall.NLM_F_DUMP)
+	req.addData(make([]byte, syscall.SizeofIfAddrmsg))
+	msgs, err := s.execute(req, syscall.RTM_NEWADDR)
+	if err != nil {
+		return
+	}
+	for _, m := range msgs {
+		if entry, ok := addressEntry(m, link.Index); ok {
+			config.Entries = append(config.Entries, entry)
+		}
+	}
+
+	req = newNetlinkRequest(syscall.RTM_GETROUTE, syscall.NLM_F_DUMP)
+	req.addData(make([]byte, syscall.SizeofRtMsg))
+	msgs, err = s.execute(req, syscall.RTM_NEWROUTE)
+	if err != nil {
+		return
+	}
+	for _, m := range msgs {
+		if entry, ok := routeEntry(m, link.Index); ok {
+			config.Entries = append(config.Entries, entry)
+		}
+	}
+
+	ndm := make([]byte, sizeofNdMsg)
+	ndm[10] = NTF_PROXY
+	req = newNetlinkRequest(syscall.RTM_GETNEIGH, syscall.NLM_F_DUMP)
+	req.addData(ndm)
+	msgs, err = s.execute(req, syscall.RTM_NEWNEIGH)
+	if err != nil {
+		return
+	}
+	for _, m := range msgs {
+		if entry, ok := neighborProxyEntry(m, link.Index); ok {
+			config.Entries = append(config.Entries, entry)
+		}
+	}
+	return
+}
+
+// RestoreNetConfig reinstalls every entry of config. Routes can depend on
+// each other, so entries that fail are retried once after the rest went in.
+func (b NetlinkBackend) RestoreNetConfig(link Link, config NetConfig) (failures []RestoreFailure) {
+	s, err := b.open()
+	if err != nil {
+		for _, entry := range config.Entries {
+			failures = append(failures, RestoreFailure{entry, err})
+		}
+		return
+	}
+	defer s.Close()
+	pending := config.Entries
+	for pass := 0; pass < 2 && len(pending) > 0; pass++ {
+		failures = nil
+		for _, entry := range pending {
+			req := newNetlinkRequest(entry.msgType, syscall.NLM_F_ACK|syscall.NLM_F_CREATE|syscall.NLM_F_REPLACE)
+			req.addData(entry.data)
+			_, err := s.execute(req, entry.msgType)
+			if err != nil {
+				failures = append(failures, RestoreFailure{entry, err})
+			}
+		}
+		pending = nil
+		for _, failure := range failures {
+			pending = append(pending, failure.Entry)
+		}
+	}
+	return
+}
+
+func addressEntry(m syscall.NetlinkMessage, index int) (entry NetConfigEntry, ok bool) {
+	if len(m.Data) < syscall.SizeofIfAddrmsg {
+		return
+	}
+	family, prefixLen, flags, scope := m.Data[0], m.Data[1], m.Data[2], m.Data[3]
+	if int(binary.NativeEndian.Uint32(m.Data[4:8])) != index {
+		return
+	}
+	// Link-local and autoconfigured IPv6 addresses are derived from the
+	// MAC, the kernel generates the new ones on its own
+	if family == syscall.AF_INET6 && (scope == syscall.RT_SCOPE_LINK || flags&syscall.IFA_F_PERMANENT == 0) {
+		return
+	}
+	attrs := parseAttrs(m.Data[syscall.SizeofIfAddrmsg:])
+	addr, found := attrs[syscall.IFA_LOCAL]
+	if !found {
+		addr = attrs[syscall.IFA_ADDRESS]
+	}
+	entry = NetConfigEntry{
+		Kind:        NetConfigAddress,
+		Description: fmt.Sprintf("%s/%d", net.IP(addr), prefixLen),
+		msgType:     syscall.RTM_NEWADDR,
+		data:        append([]byte(nil), m.Data...),
+	}
+	ok = true
+	return
+}
+
+func routeEntry(m syscall.NetlinkMessage, index int) (entry NetConfigEntry, ok bool) {
+	if len(m.Data) < syscall.SizeofRtMsg {
+		return
+	}
+	dstLen, table, protocol := m.Data[1], m.Data[4], m.Data[5]
+	// Kernel and router advertisement routes come back by themselves
+	if table == syscall.RT_TABLE_LOCAL || protocol == syscall.RTPROT_KERNEL || protocol == syscall.RTPROT_RA {
+		return
+	}
+	attrs := parseAttrs(m.Data[syscall.SizeofRtMsg:])
+	oif, found := attrs[syscall.RTA_OIF]
+	if !found || len(oif) < 4 || int(binary.NativeEndian.Uint32(oif)) != index {
+		return
+	}
+	description := "default"
+	if dst, found := attrs[syscall.RTA_DST]; found {
+		description = fmt.Sprintf("%s/%d", net.IP(dst), dstLen)
+	}
+	if gw, found := attrs[syscall.RTA_GATEWAY]; found {
+		description += fmt.Sprintf(" via %s", net.IP(gw))
+	}
+	entry = NetConfigEntry{
+		Kind:        NetConfigRoute,
+		Description: description,
+		msgType:     syscall.RTM_NEWROUTE,
+		data:        append([]byte(nil), m.Data...),
+	}
+	ok = true
+	return
+}
+
+func neighborProxyEntry(m syscall.NetlinkMessage, index int) (entry NetConfigEntry, ok bool) {
+	if len(m.Data) < sizeofNdMsg {
+		return
+	}
+	if int(int32(binary.NativeEndian.Uint32(m.Data[4:8]))) != index || m.Data[10]&NTF_PROXY == 0 {
+		return
+	}
+	attrs := parseAttrs(m.Data[sizeofNdMsg:])
+	entry = NetConfigEntry{
+		Kind:        NetConfigNeighborProxy,
+		Description: net.IP(attrs[NDA_DST]).String(),
+		msgType:     syscall.RTM_NEWNEIGH,
+		data:        append([]byte(nil), m.Data...),
+	}
+	ok = true
+	return
+}
+
+// parseAttrs splits a run of rtattrs, nested attributes are left as is
+func parseAttrs(b []byte) (attrs map[uint16][]byte) {
+	attrs = make(map[uint16][]byte)
+	for len(b) >= syscall.SizeofRtAttr {
+		attrLen := int(binary.NativeEndian.Uint16(b[0:2]))
+		attrType := binary.NativeEndian.Uint16(b[2:4]) & NLA_TYPE_MASK
+		if attrLen < syscall.SizeofRtAttr || attrLen > len(b) {
+			return
+		}
+		attrs[attrType] = b[syscall.SizeofRtAttr:attrLen]
+		if nlmAlign(attrLen) >= len(b) {
+			return
+		}
+		b = b[nlmAlign(attrLen):]
+	}
+	return
+}
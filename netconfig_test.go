@@ -0,0 +1,72 @@
+package libmacouflage
+
+import (
+	"encoding/binary"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildAddrMessage(family uint8, prefixLen uint8, flags uint8, scope uint8, index int, addr net.IP) syscall.NetlinkMessage {
+	req := newNetlinkRequest(syscall.RTM_NEWADDR, 0)
+	ifa := make([]byte, syscall.SizeofIfAddrmsg)
+	ifa[0], ifa[1], ifa[2], ifa[3] = family, prefixLen, flags, scope
+	binary.NativeEndian.PutUint32(ifa[4:8], uint32(index))
+	req.addData(ifa)
+	req.addAttr(syscall.IFA_LOCAL, addr)
+	return syscall.NetlinkMessage{Data: req.data}
+}
+
+func buildRouteMessage(protocol uint8, table uint8, index int, gw net.IP) syscall.NetlinkMessage {
+	req := newNetlinkRequest(syscall.RTM_NEWROUTE, 0)
+	rtm := make([]byte, syscall.SizeofRtMsg)
+	rtm[0], rtm[4], rtm[5] = syscall.AF_INET, table, protocol
+	req.addData(rtm)
+	oif := make([]byte, 4)
+	binary.NativeEndian.PutUint32(oif, uint32(index))
+	req.addAttr(syscall.RTA_OIF, oif)
+	req.addAttr(syscall.RTA_GATEWAY, gw.To4())
+	return syscall.NetlinkMessage{Data: req.data}
+}
+
+func Test_AddressEntry_1(t *testing.T) {
+	m := buildAddrMessage(syscall.AF_INET, 24, 0, 0, 3, net.ParseIP("10.0.0.2").To4())
+	entry, ok := addressEntry(m, 3)
+	assert.True(t, ok)
+	assert.Equal(t, "address 10.0.0.2/24", entry.String())
+	_, ok = addressEntry(m, 4)
+	assert.False(t, ok, "Address of another interface was kept")
+}
+
+func Test_AddressEntry_2(t *testing.T) {
+	m := buildAddrMessage(syscall.AF_INET6, 64, syscall.IFA_F_PERMANENT, syscall.RT_SCOPE_LINK, 3, net.ParseIP("fe80::1"))
+	_, ok := addressEntry(m, 3)
+	assert.False(t, ok, "Link-local address was kept")
+	m = buildAddrMessage(syscall.AF_INET6, 64, 0, 0, 3, net.ParseIP("2001:db8::1"))
+	_, ok = addressEntry(m, 3)
+	assert.False(t, ok, "Autoconfigured address was kept")
+	m = buildAddrMessage(syscall.AF_INET6, 64, syscall.IFA_F_PERMANENT, 0, 3, net.ParseIP("2001:db8::1"))
+	_, ok = addressEntry(m, 3)
+	assert.True(t, ok)
+}
+
+func Test_RouteEntry_1(t *testing.T) {
+	m := buildRouteMessage(syscall.RTPROT_BOOT, syscall.RT_TABLE_MAIN, 3, net.ParseIP("10.0.0.1"))
+	entry, ok := routeEntry(m, 3)
+	assert.True(t, ok)
+	assert.Equal(t, "route default via 10.0.0.1", entry.String())
+	m = buildRouteMessage(syscall.RTPROT_KERNEL, syscall.RT_TABLE_MAIN, 3, net.ParseIP("10.0.0.1"))
+	_, ok = routeEntry(m, 3)
+	assert.False(t, ok, "Kernel route was kept")
+}
+
+func Test_SetMacWithOptions_PreserveNetConfig_1(t *testing.T) {
+	backend := newRecordingBackend("rec0")
+	h := NewHandle(backend)
+	_, err := h.SetMacWithOptions("rec0", "00:11:22:00:00:01",
+		SetMacOptions{AutoDown: true, PreserveNetConfig: true})
+	assert.Error(t, err, "Function failed to generate error for unsupported backend")
+	assert.Empty(t, backend.calls)
+}
@@ -0,0 +1,390 @@
+package libmacouflage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"syscall"
+)
+
+const (
+	SOL_NETLINK     = 270
+	NETLINK_EXT_ACK = 11
+
+	NLM_F_CAPPED   = 0x100
+	NLM_F_ACK_TLVS = 0x200
+
+	NLMSGERR_ATTR_MSG  = 1
+	NLMSGERR_ATTR_OFFS = 2
+
+	IFLA_CARRIER      = 33
+	IFLA_PERM_ADDRESS = 54
+
+	IFF_LOWER_UP = 0x10000
+)
+
+// NetlinkBackend talks rtnetlink to the kernel. Links are addressed by
+// interface index, so a rename between a lookup and a set cannot redirect
+// the change to another interface.
+type NetlinkBackend struct {
+	// NetNS is the namespace the backend operates in, the zero value is
+	// the caller's namespace
+	NetNS NetNS
+}
+
+// NetlinkError is an error reported by the kernel in an NLMSG_ERROR
+// message, including the extended ACK message when the kernel sent one.
+type NetlinkError struct {
+	Errno   syscall.Errno
+	Message string
+}
+
+func (e NetlinkError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%s: %s", e.Message, e.Errno.Error())
+	}
+	return e.Errno.Error()
+}
+
+func (e NetlinkError) Unwrap() error {
+	return e.Errno
+}
+
+var netlinkSeq uint32
+
+type netlinkSocket struct {
+	fd  int
+	pid uint32
+}
+
+type netlinkRequest struct {
+	msgType uint16
+	flags   uint16
+	data    []byte
+}
+
+func openNetlinkSocket() (s *netlinkSocket, err error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return
+	}
+	// Extended ACK is best effort, older kernels reject the option
+	syscall.SetsockoptInt(fd, SOL_NETLINK, NETLINK_EXT_ACK, 1)
+	lsa := &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}
+	err = syscall.Bind(fd, lsa)
+	if err != nil {
+		syscall.Close(fd)
+		return
+	}
+	sa, err := syscall.Getsockname(fd)
+	if err != nil {
+		syscall.Close(fd)
+		return
+	}
+	s = &netlinkSocket{fd: fd, pid: sa.(*syscall.SockaddrNetlink).Pid}
+	return
+}
+
+func (s *netlinkSocket) Close() error {
+	return syscall.Close(s.fd)
+}
+
+func newNetlinkRequest(msgType uint16, flags uint16) *netlinkRequest {
+	return &netlinkRequest{msgType: msgType, flags: flags | syscall.NLM_F_REQUEST}
+}
+
+func (r *netlinkRequest) addData(data []byte) {
+	r.data = append(r.data, data...)
+	for len(r.data)%syscall.NLMSG_ALIGNTO != 0 {
+		r.data = append(r.data, 0)
+	}
+}
+
+func (r *netlinkRequest) addAttr(attrType uint16, value []byte) {
+	buf := make([]byte, syscall.SizeofRtAttr+len(value))
+	binary.NativeEndian.PutUint16(buf[0:2], uint16(len(buf)))
+	binary.NativeEndian.PutUint16(buf[2:4], attrType)
+	copy(buf[syscall.SizeofRtAttr:], value)
+	r.addData(buf)
+}
+
+func (r *netlinkRequest) serialize(seq uint32) []byte {
+	buf := make([]byte, syscall.SizeofNlMsghdr+len(r.data))
+	binary.NativeEndian.PutUint32(buf[0:4], uint32(len(buf)))
+	binary.NativeEndian.PutUint16(buf[4:6], r.msgType)
+	binary.NativeEndian.PutUint16(buf[6:8], r.flags)
+	binary.NativeEndian.PutUint32(buf[8:12], seq)
+	copy(buf[syscall.SizeofNlMsghdr:], r.data)
+	return buf
+}
+
+// execute sends req and collects the replies of type resType. Requests
+// sent with NLM_F_ACK or NLM_F_DUMP are read until the kernel terminates
+// them, so errors always surface here.
+func (s *netlinkSocket) execute(req *netlinkRequest, resType uint16) (msgs []syscall.NetlinkMessage, err error) {
+	seq := atomic.AddUint32(&netlinkSeq, 1)
+	err = syscall.Sendto(s.fd, req.serialize(seq), 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+	if err != nil {
+		return
+	}
+	for {
+		// Replies keep referencing buf, it cannot be reused between reads
+		buf := make([]byte, 32*1024)
+		n, from, rerr := syscall.Recvfrom(s.fd, buf, 0)
+		if rerr != nil {
+			err = rerr
+			return
+		}
+		if sa, ok := from.(*syscall.SockaddrNetlink); !ok || sa.Pid != 0 {
+			continue
+		}
+		if n < syscall.NLMSG_HDRLEN {
+			err = fmt.Errorf("Short netlink response: %d bytes", n)
+			return
+		}
+		replies, perr := syscall.ParseNetlinkMessage(buf[:n])
+		if perr != nil {
+			err = perr
+			return
+		}
+		for _, m := range replies {
+			if m.Header.Seq != seq || m.Header.Pid != s.pid {
+				continue
+			}
+			switch m.Header.Type {
+			case syscall.NLMSG_DONE:
+				return
+			case syscall.NLMSG_ERROR:
+				err = parseNetlinkError(m)
+				return
+			case resType:
+				msgs = append(msgs, m)
+			}
+			if m.Header.Flags&syscall.NLM_F_MULTI == 0 && req.flags&syscall.NLM_F_ACK == 0 {
+				return
+			}
+		}
+	}
+}
+
+// parseNetlinkError decodes an NLMSG_ERROR payload. A zero error code is
+// a plain ACK and yields nil.
+func parseNetlinkError(m syscall.NetlinkMessage) error {
+	if len(m.Data) < 4 {
+		return fmt.Errorf("Truncated netlink error message")
+	}
+	code := int32(binary.NativeEndian.Uint32(m.Data[0:4]))
+	if code == 0 {
+		return nil
+	}
+	nlerr := NetlinkError{Errno: syscall.Errno(-code)}
+	if m.Header.Flags&NLM_F_ACK_TLVS == 0 {
+		return nlerr
+	}
+	// Skip the echoed request, only its header when the kernel capped it
+	offset := 4 + syscall.NLMSG_HDRLEN
+	if m.Header.Flags&NLM_F_CAPPED == 0 && len(m.Data) >= 4+syscall.NLMSG_HDRLEN {
+		offset = 4 + nlmAlign(int(binary.NativeEndian.Uint32(m.Data[4:8])))
+	}
+	for offset+syscall.SizeofRtAttr <= len(m.Data) {
+		attrLen := int(binary.NativeEndian.Uint16(m.Data[offset : offset+2]))
+		attrType := binary.NativeEndian.Uint16(m.Data[offset+2 : offset+4])
+		if attrLen < syscall.SizeofRtAttr || offset+attrLen > len(m.Data) {
+			break
+		}
+		if attrType == NLMSGERR_ATTR_MSG {
+			value := m.Data[offset+syscall.SizeofRtAttr : offset+attrLen]
+			nlerr.Message = strings.TrimRight(string(value), "\x00")
+		}
+		offset += nlmAlign(attrLen)
+	}
+	return nlerr
+}
+
+func nlmAlign(n int) int {
+	return (n + syscall.NLMSG_ALIGNTO - 1) &^ (syscall.NLMSG_ALIGNTO - 1)
+}
+
+func serializeIfInfomsg(msg syscall.IfInfomsg) []byte {
+	buf := make([]byte, syscall.SizeofIfInfomsg)
+	buf[0] = msg.Family
+	binary.NativeEndian.PutUint16(buf[2:4], msg.Type)
+	binary.NativeEndian.PutUint32(buf[4:8], uint32(msg.Index))
+	binary.NativeEndian.PutUint32(buf[8:12], msg.Flags)
+	binary.NativeEndian.PutUint32(buf[12:16], msg.Change)
+	return buf
+}
+
+func parseLinkMessage(m syscall.NetlinkMessage) (link Link, err error) {
+	if len(m.Data) < syscall.SizeofIfInfomsg {
+		err = fmt.Errorf("Truncated link message")
+		return
+	}
+	link.HardwareType = binary.NativeEndian.Uint16(m.Data[2:4])
+	link.Index = int(int32(binary.NativeEndian.Uint32(m.Data[4:8])))
+	rawFlags := binary.NativeEndian.Uint32(m.Data[8:12])
+	link.Flags = linkFlags(rawFlags)
+	link.Carrier = rawFlags&IFF_LOWER_UP != 0
+	attrs, err := syscall.ParseNetlinkRouteAttr(&m)
+	if err != nil {
+		return
+	}
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case syscall.IFLA_IFNAME:
+			link.Name = strings.TrimRight(string(attr.Value), "\x00")
+		case syscall.IFLA_MTU:
+			if len(attr.Value) >= 4 {
+				link.MTU = int(binary.NativeEndian.Uint32(attr.Value))
+			}
+		case syscall.IFLA_ADDRESS:
+			link.HardwareAddr = append(net.HardwareAddr(nil), attr.Value...)
+		case syscall.IFLA_OPERSTATE:
+			if len(attr.Value) >= 1 {
+				link.OperState = OperState(attr.Value[0])
+			}
+		case IFLA_CARRIER:
+			if len(attr.Value) >= 1 {
+				link.Carrier = attr.Value[0] != 0
+			}
+		case IFLA_PERM_ADDRESS:
+			link.PermHardwareAddr = append(net.HardwareAddr(nil), attr.Value...)
+		}
+	}
+	return
+}
+
+func linkFlags(rawFlags uint32) (flags net.Flags) {
+	if rawFlags&syscall.IFF_UP != 0 {
+		flags |= net.FlagUp
+	}
+	if rawFlags&syscall.IFF_BROADCAST != 0 {
+		flags |= net.FlagBroadcast
+	}
+	if rawFlags&syscall.IFF_LOOPBACK != 0 {
+		flags |= net.FlagLoopback
+	}
+	if rawFlags&syscall.IFF_POINTOPOINT != 0 {
+		flags |= net.FlagPointToPoint
+	}
+	if rawFlags&syscall.IFF_MULTICAST != 0 {
+		flags |= net.FlagMulticast
+	}
+	if rawFlags&syscall.IFF_RUNNING != 0 {
+		flags |= net.FlagRunning
+	}
+	return
+}
+
+func (b NetlinkBackend) open() (s *netlinkSocket, err error) {
+	err = b.NetNS.Do(func() (err error) {
+		s, err = openNetlinkSocket()
+		return
+	})
+	return
+}
+
+func (b NetlinkBackend) Links() (links []Link, err error) {
+	s, err := b.open()
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	req := newNetlinkRequest(syscall.RTM_GETLINK, syscall.NLM_F_DUMP)
+	req.addData(serializeIfInfomsg(syscall.IfInfomsg{Family: syscall.AF_UNSPEC}))
+	msgs, err := s.execute(req, syscall.RTM_NEWLINK)
+	if err != nil {
+		return
+	}
+	for _, m := range msgs {
+		link, perr := parseLinkMessage(m)
+		if perr != nil {
+			err = perr
+			return
+		}
+		links = append(links, link)
+	}
+	return
+}
+
+func (b NetlinkBackend) LinkByName(name string) (link Link, err error) {
+	s, err := b.open()
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	req := newNetlinkRequest(syscall.RTM_GETLINK, 0)
+	req.addData(serializeIfInfomsg(syscall.IfInfomsg{Family: syscall.AF_UNSPEC}))
+	req.addAttr(syscall.IFLA_IFNAME, append([]byte(name), 0))
+	msgs, err := s.execute(req, syscall.RTM_NEWLINK)
+	if err != nil {
+		return
+	}
+	if len(msgs) == 0 {
+		err = syscall.ENODEV
+		return
+	}
+	link, err = parseLinkMessage(msgs[0])
+	return
+}
+
+func (b NetlinkBackend) LinkByIndex(index int) (link Link, err error) {
+	s, err := b.open()
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	req := newNetlinkRequest(syscall.RTM_GETLINK, 0)
+	req.addData(serializeIfInfomsg(syscall.IfInfomsg{
+		Family: syscall.AF_UNSPEC,
+		Index:  int32(index),
+	}))
+	msgs, err := s.execute(req, syscall.RTM_NEWLINK)
+	if err != nil {
+		return
+	}
+	if len(msgs) == 0 {
+		err = syscall.ENODEV
+		return
+	}
+	link, err = parseLinkMessage(msgs[0])
+	return
+}
+
+func (b NetlinkBackend) SetHardwareAddr(link Link, mac net.HardwareAddr) (err error) {
+	s, err := b.open()
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	req := newNetlinkRequest(syscall.RTM_NEWLINK, syscall.NLM_F_ACK)
+	req.addData(serializeIfInfomsg(syscall.IfInfomsg{
+		Family: syscall.AF_UNSPEC,
+		Index:  int32(link.Index),
+	}))
+	req.addAttr(syscall.IFLA_ADDRESS, mac)
+	_, err = s.execute(req, syscall.RTM_NEWLINK)
+	return
+}
+
+func (b NetlinkBackend) SetAdminState(link Link, up bool) (err error) {
+	s, err := b.open()
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	msg := syscall.IfInfomsg{
+		Family: syscall.AF_UNSPEC,
+		Index:  int32(link.Index),
+		Change: syscall.IFF_UP,
+	}
+	if up {
+		msg.Flags = syscall.IFF_UP
+	}
+	req := newNetlinkRequest(syscall.RTM_NEWLINK, syscall.NLM_F_ACK)
+	req.addData(serializeIfInfomsg(msg))
+	_, err = s.execute(req, syscall.RTM_NEWLINK)
+	return
+}
@@ -0,0 +1,53 @@
+package libmacouflage
+
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildNetlinkError(code int32, flags uint16, tlvs []byte) syscall.NetlinkMessage {
+	data := make([]byte, 4+syscall.NLMSG_HDRLEN)
+	binary.NativeEndian.PutUint32(data[0:4], uint32(code))
+	binary.NativeEndian.PutUint32(data[4:8], syscall.NLMSG_HDRLEN)
+	data = append(data, tlvs...)
+	return syscall.NetlinkMessage{
+		Header: syscall.NlMsghdr{Type: syscall.NLMSG_ERROR, Flags: flags},
+		Data:   data,
+	}
+}
+
+func Test_ParseNetlinkError_1(t *testing.T) {
+	err := parseNetlinkError(buildNetlinkError(0, 0, nil))
+	assert.NoError(t, err, "Plain ACK should not produce an error")
+}
+
+func Test_ParseNetlinkError_2(t *testing.T) {
+	err := parseNetlinkError(buildNetlinkError(-int32(syscall.EBUSY), 0, nil))
+	assert.Equal(t, NetlinkError{Errno: syscall.EBUSY}, err)
+}
+
+func Test_ParseNetlinkError_3(t *testing.T) {
+	req := newNetlinkRequest(0, 0)
+	req.addAttr(NLMSGERR_ATTR_MSG, append([]byte("Cannot change address"), 0))
+	err := parseNetlinkError(buildNetlinkError(-int32(syscall.EBUSY), NLM_F_CAPPED|NLM_F_ACK_TLVS, req.data))
+	nlerr, ok := err.(NetlinkError)
+	assert.True(t, ok, "err is not of type NetlinkError")
+	assert.Equal(t, syscall.EBUSY, nlerr.Errno)
+	assert.Equal(t, "Cannot change address", nlerr.Message)
+}
+
+func Test_NetlinkBackend_LinkByName_1(t *testing.T) {
+	_, err := NetlinkBackend{}.LinkByName("badinterface")
+	assert.Error(t, err, "Function failed to generate error for bad interface")
+}
+
+func Test_NetlinkBackend_Links_1(t *testing.T) {
+	links, err := NetlinkBackend{}.Links()
+	assert.NoError(t, err)
+	ifaces, err := GetInterfaces()
+	assert.NoError(t, err)
+	assert.True(t, len(links) >= len(ifaces))
+}
@@ -0,0 +1,144 @@
+package libmacouflage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+var netnsRunDir = "/var/run/netns"
+
+// NetNS identifies a network namespace. The zero value is the namespace of
+// the calling process.
+type NetNS struct {
+	path  string
+	fd    int
+	hasFd bool
+}
+
+// NetNSByName refers to a namespace created by `ip netns add`.
+func NetNSByName(name string) NetNS {
+	return NetNS{path: filepath.Join(netnsRunDir, name)}
+}
+
+// NetNSByPath refers to a namespace file such as /proc/<pid>/ns/net or a
+// bind mount of one.
+func NetNSByPath(path string) NetNS {
+	return NetNS{path: path}
+}
+
+// NetNSByPid refers to the namespace the process pid is running in.
+func NetNSByPid(pid int) NetNS {
+	return NetNS{path: fmt.Sprintf("/proc/%d/ns/net", pid)}
+}
+
+// NetNSByFd refers to an open namespace file descriptor. The descriptor
+// stays owned by the caller.
+func NetNSByFd(fd int) NetNS {
+	return NetNS{fd: fd, hasFd: true}
+}
+
+func (ns NetNS) IsCurrent() bool {
+	return ns.path == "" && !ns.hasFd
+}
+
+func (ns NetNS) String() string {
+	if ns.hasFd {
+		return fmt.Sprintf("fd:%d", ns.fd)
+	}
+	if ns.path == "" {
+		return "current"
+	}
+	return ns.path
+}
+
+// ID returns the device and inode of the namespace, which identify it
+// regardless of how it was referred to.
+func (ns NetNS) ID() (id string, err error) {
+	var st syscall.Stat_t
+	switch {
+	case ns.hasFd:
+		err = syscall.Fstat(ns.fd, &st)
+	case ns.path == "":
+		err = syscall.Stat("/proc/self/ns/net", &st)
+	default:
+		err = syscall.Stat(ns.path, &st)
+	}
+	if err != nil {
+		return
+	}
+	id = fmt.Sprintf("%d:%d", st.Dev, st.Ino)
+	return
+}
+
+// withFile calls f with an open descriptor of the namespace file
+func (ns NetNS) withFile(f func(fd int) error) error {
+	if ns.hasFd {
+		return f(ns.fd)
+	}
+	path := ns.path
+	if path == "" {
+		path = "/proc/self/ns/net"
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return f(int(file.Fd()))
+}
+
+// Do runs f on an OS thread that has been moved into the namespace.
+// Sockets created by f stay bound to the namespace after Do returns.
+func (ns NetNS) Do(f func() error) (err error) {
+	if ns.IsCurrent() {
+		return f()
+	}
+	fd := ns.fd
+	if !ns.hasFd {
+		file, oerr := os.Open(ns.path)
+		if oerr != nil {
+			return oerr
+		}
+		defer file.Close()
+		fd = int(file.Fd())
+	}
+	// A fresh goroutine owns the thread, so if it cannot be moved back
+	// the runtime discards the thread instead of reusing it
+	result := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		origin, err := os.Open(fmt.Sprintf("/proc/self/task/%d/ns/net", syscall.Gettid()))
+		if err != nil {
+			runtime.UnlockOSThread()
+			result <- err
+			return
+		}
+		defer origin.Close()
+		err = setns(fd)
+		if err != nil {
+			runtime.UnlockOSThread()
+			result <- fmt.Errorf("Cannot enter network namespace %s: %w", ns, err)
+			return
+		}
+		err = f()
+		if rerr := setns(int(origin.Fd())); rerr != nil {
+			result <- fmt.Errorf("Cannot leave network namespace %s: %w", ns, rerr)
+			return
+		}
+		runtime.UnlockOSThread()
+		result <- err
+	}()
+	err = <-result
+	return
+}
+
+func setns(fd int) (err error) {
+	_, _, errno := syscall.Syscall(SYS_SETNS, uintptr(fd), syscall.CLONE_NEWNET, 0)
+	if errno != 0 {
+		err = syscall.Errno(errno)
+	}
+	return
+}
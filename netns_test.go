@@ -0,0 +1,46 @@
+package libmacouflage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NetNS_IsCurrent_1(t *testing.T) {
+	assert.True(t, NetNS{}.IsCurrent())
+	assert.False(t, NetNSByName("test").IsCurrent())
+	assert.False(t, NetNSByFd(0).IsCurrent())
+}
+
+func Test_NetNSByName_1(t *testing.T) {
+	assert.Equal(t, "/var/run/netns/test", NetNSByName("test").String())
+}
+
+func Test_NewHandleAt_1(t *testing.T) {
+	_, err := NewHandleAt(NetNSByName("badnamespace"))
+	assert.Error(t, err, "Function failed to generate error for bad namespace")
+}
+
+func Test_NewHandleAt_2(t *testing.T) {
+	h, err := NewHandleAt(NetNSByPid(os.Getpid()))
+	assert.NoError(t, err)
+	if err != nil {
+		return
+	}
+	ifaces, err := h.GetInterfaces()
+	assert.NoError(t, err)
+	expected, err := GetInterfaces()
+	assert.NoError(t, err)
+	assert.Equal(t, len(expected), len(ifaces))
+}
+
+func Test_NetNS_Do_1(t *testing.T) {
+	called := false
+	err := NetNSByName("badnamespace").Do(func() error {
+		called = true
+		return nil
+	})
+	assert.Error(t, err, "Function failed to generate error for bad namespace")
+	assert.False(t, called)
+}
@@ -0,0 +1,190 @@
+package libmacouflage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+const NDA_LLADDR = 2
+
+// Network tells apart the networks an interface attaches to: the subnet of
+// the interface and the gateway of its default route.
+type Network struct {
+	Subnet     *net.IPNet
+	Gateway    net.IP
+	GatewayMac net.HardwareAddr
+}
+
+// NetworkBackend is implemented by backends that can find the network a
+// link attaches to.
+type NetworkBackend interface {
+	LinkNetwork(link Link) (Network, error)
+}
+
+// ID is the network identifier of the network, the MAC of the gateway and
+// the subnet. It changes when either does.
+func (n Network) ID() []byte {
+	return []byte(n.GatewayMac.String() + " " + n.Subnet.String())
+}
+
+func LinkNetwork(name string) (network Network, err error) {
+	return defaultHandle().LinkNetwork(name)
+}
+
+// LinkNetwork returns the network the interface called name attaches to. The
+// gateway must be in the neighbor table, which it is once traffic went
+// through it.
+func (h *Handle) LinkNetwork(name string) (network Network, err error) {
+	link, err := h.lookup(name)
+	if err != nil {
+		return
+	}
+	networkBackend, ok := h.backend.(NetworkBackend)
+	if !ok {
+		err = LinkError{ErrNotSupported, "network", name, nil}
+		return
+	}
+	network, err = networkBackend.LinkNetwork(link)
+	if err != nil {
+		err = LinkError{ErrNoNetwork, "network", name, err}
+	}
+	return
+}
+
+// LinkNetwork takes the default route of link with the lowest metric, IPv4
+// first, the address of link in the same family and the neighbor entry of
+// the gateway.
+func (b NetlinkBackend) LinkNetwork(link Link) (network Network, err error) {
+	s, err := b.open()
+	if err != nil {
+		return
+	}
+	defer s.Close()
+
+	req := newNetlinkRequest(syscall.RTM_GETROUTE, syscall.NLM_F_DUMP)
+	req.addData(make([]byte, syscall.SizeofRtMsg))
+	msgs, err := s.execute(req, syscall.RTM_NEWROUTE)
+	if err != nil {
+		return
+	}
+	var family byte
+	var metric uint32
+	for _, m := range msgs {
+		gw, routeFamily, routeMetric, ok := defaultGateway(m, link.Index)
+		if !ok {
+			continue
+		}
+		better := network.Gateway == nil ||
+			(routeFamily == syscall.AF_INET && family != syscall.AF_INET) ||
+			(routeFamily == family && routeMetric < metric)
+		if better {
+			network.Gateway, family, metric = gw, routeFamily, routeMetric
+		}
+	}
+	if network.Gateway == nil {
+		err = fmt.Errorf("No default route through %s", link.Name)
+		return
+	}
+
+	req = newNetlinkRequest(syscall.RTM_GETADDR, syscall.NLM_F_DUMP)
+	ifa := make([]byte, syscall.SizeofIfAddrmsg)
+	ifa[0] = family
+	req.addData(ifa)
+	msgs, err = s.execute(req, syscall.RTM_NEWADDR)
+	if err != nil {
+		return
+	}
+	for _, m := range msgs {
+		subnet, ok := linkSubnet(m, link.Index, family)
+		if !ok {
+			continue
+		}
+		// An IPv6 gateway is link-local, the subnet is the global prefix
+		if network.Subnet == nil || subnet.Contains(network.Gateway) {
+			network.Subnet = subnet
+		}
+	}
+	if network.Subnet == nil {
+		err = fmt.Errorf("No address on %s", link.Name)
+		return
+	}
+
+	ndm := make([]byte, sizeofNdMsg)
+	ndm[0] = family
+	req = newNetlinkRequest(syscall.RTM_GETNEIGH, syscall.NLM_F_DUMP)
+	req.addData(ndm)
+	msgs, err = s.execute(req, syscall.RTM_NEWNEIGH)
+	if err != nil {
+		return
+	}
+	for _, m := range msgs {
+		if len(m.Data) < sizeofNdMsg || int(int32(binary.NativeEndian.Uint32(m.Data[4:8]))) != link.Index {
+			continue
+		}
+		attrs := parseAttrs(m.Data[sizeofNdMsg:])
+		lladdr, found := attrs[NDA_LLADDR]
+		if found && !isZeroMac(lladdr) && net.IP(attrs[NDA_DST]).Equal(network.Gateway) {
+			network.GatewayMac = append(net.HardwareAddr(nil), lladdr...)
+			return
+		}
+	}
+	err = fmt.Errorf("Gateway %s is not in the neighbor table", network.Gateway)
+	return
+}
+
+// defaultGateway returns the gateway of m if it is a default route of the
+// main table through the link index
+func defaultGateway(m syscall.NetlinkMessage, index int) (gw net.IP, family byte, metric uint32, ok bool) {
+	if len(m.Data) < syscall.SizeofRtMsg {
+		return
+	}
+	family, dstLen, table := m.Data[0], m.Data[1], uint32(m.Data[4])
+	if dstLen != 0 {
+		return
+	}
+	attrs := parseAttrs(m.Data[syscall.SizeofRtMsg:])
+	if t, found := attrs[syscall.RTA_TABLE]; found && len(t) >= 4 {
+		table = binary.NativeEndian.Uint32(t)
+	}
+	oif, found := attrs[syscall.RTA_OIF]
+	if table != syscall.RT_TABLE_MAIN || !found || len(oif) < 4 || int(binary.NativeEndian.Uint32(oif)) != index {
+		return
+	}
+	gateway, found := attrs[syscall.RTA_GATEWAY]
+	if !found {
+		return
+	}
+	if priority, found := attrs[syscall.RTA_PRIORITY]; found && len(priority) >= 4 {
+		metric = binary.NativeEndian.Uint32(priority)
+	}
+	gw = append(net.IP(nil), gateway...)
+	ok = true
+	return
+}
+
+// linkSubnet returns the subnet of the address in m if it is a global one of
+// the link index in family
+func linkSubnet(m syscall.NetlinkMessage, index int, family byte) (subnet *net.IPNet, ok bool) {
+	if len(m.Data) < syscall.SizeofIfAddrmsg {
+		return
+	}
+	prefixLen, scope := m.Data[1], m.Data[3]
+	if m.Data[0] != family || scope != syscall.RT_SCOPE_UNIVERSE || int(binary.NativeEndian.Uint32(m.Data[4:8])) != index {
+		return
+	}
+	attrs := parseAttrs(m.Data[syscall.SizeofIfAddrmsg:])
+	addr, found := attrs[syscall.IFA_LOCAL]
+	if !found {
+		addr = attrs[syscall.IFA_ADDRESS]
+	}
+	ip := net.IP(addr)
+	mask := net.CIDRMask(int(prefixLen), len(ip)*8)
+	if mask == nil {
+		return
+	}
+	subnet = &net.IPNet{IP: ip.Mask(mask), Mask: mask}
+	ok = true
+	return
+}
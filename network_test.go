@@ -0,0 +1,47 @@
+package libmacouflage
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DefaultGateway_1(t *testing.T) {
+	m := buildRouteMessage(syscall.RTPROT_BOOT, syscall.RT_TABLE_MAIN, 3, net.ParseIP("10.0.0.1"))
+	gw, family, _, ok := defaultGateway(m, 3)
+	assert.True(t, ok)
+	assert.Equal(t, byte(syscall.AF_INET), family)
+	assert.Equal(t, "10.0.0.1", gw.String())
+	_, _, _, ok = defaultGateway(m, 4)
+	assert.False(t, ok, "Route of another interface was taken")
+	m = buildRouteMessage(syscall.RTPROT_BOOT, syscall.RT_TABLE_LOCAL, 3, net.ParseIP("10.0.0.1"))
+	_, _, _, ok = defaultGateway(m, 3)
+	assert.False(t, ok, "Route of the local table was taken")
+	subnet, ok := linkSubnet(buildAddrMessage(syscall.AF_INET, 24, 0, 0, 3, net.ParseIP("10.0.0.2").To4()), 3, syscall.AF_INET)
+	assert.True(t, ok)
+	assert.Equal(t, "10.0.0.0/24", subnet.String())
+}
+
+func Test_LinkNetwork_1(t *testing.T) {
+	backend := newTestFakeBackend()
+	h := NewHandle(backend)
+	_, err := h.LinkNetwork("fake0")
+	assert.True(t, errors.Is(err, ErrNoNetwork))
+	iface, _ := backend.Interface("fake0")
+	gatewayMac, _ := net.ParseMAC("00:00:5e:00:53:01")
+	_, subnet, _ := net.ParseCIDR("192.0.2.0/24")
+	iface.Network = Network{subnet, net.ParseIP("192.0.2.1"), gatewayMac}
+	backend.AddInterface(iface)
+	network, err := h.LinkNetwork("fake0")
+	assert.NoError(t, err)
+	assert.Equal(t, "00:00:5e:00:53:01 192.0.2.0/24", string(network.ID()))
+	first, err := h.Spoof(context.Background(), "fake0", StableStrategy([]byte("secret"), network.ID(), PrefixAny))
+	assert.NoError(t, err)
+	second, err := h.Spoof(context.Background(), "fake0", StableStrategy([]byte("secret"), network.ID(), PrefixAny))
+	assert.NoError(t, err)
+	assert.Equal(t, first.NewMac, second.NewMac)
+}
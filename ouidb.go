@@ -0,0 +1,147 @@
+package libmacouflage
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+)
+
+// Assignment types of the OuiDb entries
+const (
+	AssignmentMAL = "MA-L"
+	AssignmentMAM = "MA-M"
+	AssignmentMAS = "MA-S"
+	AssignmentCID = "CID"
+)
+
+// ouiIndex maps the prefixes of OuiDb to their first entry, by prefix length
+var ouiIndex struct {
+	sync.Mutex
+	db []Oui
+	// lengths in bits, longest first
+	lengths  []int
+	byPrefix map[int]map[string]int
+}
+
+// AssignmentType returns the kind of IEEE assignment of the entry. Entries
+// that do not record it are told by their prefix length, a 24 bit one is an
+// MA-L.
+func (o Oui) AssignmentType() string {
+	if o.Assignment != "" {
+		return o.Assignment
+	}
+	_, bits, err := o.Prefix()
+	if err != nil {
+		return ""
+	}
+	switch bits {
+	case PrefixBitsMAL:
+		return AssignmentMAL
+	case PrefixBitsMAM:
+		return AssignmentMAM
+	case PrefixBitsMAS:
+		return AssignmentMAS
+	}
+	return ""
+}
+
+// FindVendorByHardwareAddr returns the entry of OuiDb with the longest prefix
+// of mac. An MA-S block carved out of an MA-L is found before the MA-L.
+func FindVendorByHardwareAddr(mac net.HardwareAddr) (vendor Oui, err error) {
+	vendor, ok := findOui(mac)
+	if !ok {
+		msg := fmt.Sprintf("No vendor found in OuiDb for MAC: %s", mac)
+		err = NoVendorError{msg, mac.String()}
+	}
+	return
+}
+
+// ReindexOuiDb builds the index of OuiDb again, which is needed after its
+// entries are changed in place.
+func ReindexOuiDb() {
+	ouiIndex.Lock()
+	defer ouiIndex.Unlock()
+	indexOuiDb()
+}
+
+// findOui looks mac up in the index of OuiDb, which is built again when
+// OuiDb is replaced or changes length
+func findOui(mac net.HardwareAddr) (vendor Oui, ok bool) {
+	ouiIndex.Lock()
+	defer ouiIndex.Unlock()
+	if len(ouiIndex.db) != len(OuiDb) || (len(OuiDb) > 0 && &ouiIndex.db[0] != &OuiDb[0]) {
+		indexOuiDb()
+	}
+	for _, bits := range ouiIndex.lengths {
+		if bits > len(mac)*8 {
+			continue
+		}
+		if i, found := ouiIndex.byPrefix[bits][prefixKey(mac, bits)]; found {
+			return ouiIndex.db[i], true
+		}
+	}
+	return
+}
+
+// indexOuiDb builds the index of OuiDb, the first of several entries with the
+// same prefix wins
+func indexOuiDb() {
+	ouiIndex.db = OuiDb
+	ouiIndex.lengths = nil
+	ouiIndex.byPrefix = make(map[int]map[string]int)
+	for i, oui := range OuiDb {
+		prefix, bits, err := oui.Prefix()
+		if err != nil {
+			continue
+		}
+		byPrefix, ok := ouiIndex.byPrefix[bits]
+		if !ok {
+			byPrefix = make(map[string]int)
+			ouiIndex.byPrefix[bits] = byPrefix
+			ouiIndex.lengths = append(ouiIndex.lengths, bits)
+		}
+		key := prefixKey(prefix, bits)
+		if _, found := byPrefix[key]; !found {
+			byPrefix[key] = i
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ouiIndex.lengths)))
+}
+
+// prefixKey returns the first bits of mac as a map key
+func prefixKey(mac net.HardwareAddr, bits int) string {
+	key := make(net.HardwareAddr, (bits+7)/8)
+	applyPrefix(key, mac, bits)
+	return string(key)
+}
+
+// vendorBits returns the length of the assignment eui belongs to, 24 bits
+// when it is not in OuiDb
+func vendorBits(eui net.HardwareAddr) (bits int) {
+	bits = PrefixBitsMAL
+	if oui, ok := findOui(eui); ok {
+		_, bits, _ = oui.Prefix()
+	}
+	return
+}
+
+// addressBlocks returns the entries of vendors that addresses can be drawn
+// from, all but the Company IDs, which record their assignment type
+func addressBlocks(vendors []Oui) (blocks []Oui) {
+	for i, oui := range vendors {
+		if oui.Assignment != AssignmentCID {
+			if blocks != nil {
+				blocks = append(blocks, oui)
+			}
+			continue
+		}
+		if blocks == nil {
+			blocks = append(make([]Oui, 0, len(vendors)), vendors[:i]...)
+		}
+	}
+	if blocks == nil {
+		blocks = vendors
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package libmacouflage
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withOuis appends entries to OuiDb until the test ends
+func withOuis(t *testing.T, entries ...Oui) {
+	saved := OuiDb
+	OuiDb = append(append([]Oui(nil), OuiDb...), entries...)
+	t.Cleanup(func() { OuiDb = saved })
+}
+
+func Test_FindVendorByMac_LongestPrefix_1(t *testing.T) {
+	large := OuiDb[0]
+	withOuis(t,
+		Oui{VendorPrefix: large.VendorPrefix + ":12:30/36", Vendor: "Carved Out", Assignment: AssignmentMAS},
+		Oui{VendorPrefix: "1a:2b:3c", Vendor: "Company", Assignment: AssignmentCID},
+	)
+	vendor, err := FindVendorByMac(large.VendorPrefix + ":12:3f:ff")
+	assert.NoError(t, err)
+	assert.Equal(t, "Carved Out", vendor.Vendor)
+	assert.Equal(t, AssignmentMAS, vendor.AssignmentType())
+	vendor, err = FindVendorByMac(large.VendorPrefix + ":12:40:00")
+	assert.NoError(t, err)
+	assert.Equal(t, large.Vendor, vendor.Vendor)
+	assert.Equal(t, AssignmentMAL, vendor.AssignmentType())
+	mac, _ := net.ParseMAC("1a:2b:3c:00:00:01")
+	vendor, err = FindVendorByHardwareAddr(mac)
+	assert.NoError(t, err)
+	assert.Equal(t, AssignmentCID, vendor.AssignmentType())
+	_, err = FindVendorByHardwareAddr(net.HardwareAddr{0xff, 0xff, 0xfe})
+	assert.True(t, errors.Is(err, ErrNoVendor))
+	deviceType, err := FindDeviceTypeByMac(large.VendorPrefix + ":12:30:00")
+	assert.NoError(t, err)
+	assert.Equal(t, "Other", deviceType)
+}
+
+func Test_AssignmentType_1(t *testing.T) {
+	assert.Equal(t, AssignmentMAM, Oui{VendorPrefix: "00:55:da:50/28"}.AssignmentType())
+	assert.Equal(t, AssignmentMAL, Oui{VendorPrefix: "00:55:da"}.AssignmentType())
+	assert.Equal(t, "", Oui{VendorPrefix: "00:55:da:50/32"}.AssignmentType())
+	blocks := addressBlocks([]Oui{{VendorPrefix: "1a:2b:3c", Assignment: AssignmentCID}, {VendorPrefix: "00:55:da"}})
+	assert.Len(t, blocks, 1)
+	_, err := randomVendor([]Oui{{VendorPrefix: "1a:2b:3c", Assignment: AssignmentCID}})
+	assert.True(t, errors.Is(err, ErrNoVendor))
+}
+
+func Test_SameVendorStrategy_1(t *testing.T) {
+	withOuis(t, Oui{VendorPrefix: "00:55:da:50/28", Vendor: "Medium Block"})
+	current, _ := net.ParseMAC("00:55:da:5f:ff:ff")
+	for i := 0; i < 20; i++ {
+		mac, err := SameVendorStrategy(true).Generate(current, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "00:55:da:5", mac.String()[:10])
+	}
+}
+
+func Test_ReindexOuiDb_1(t *testing.T) {
+	withOuis(t, Oui{VendorPrefix: "1a:2b:3c", Vendor: "Renumbered"})
+	_, err := FindVendorByMac("1a:2b:3c:00:00:01")
+	assert.NoError(t, err)
+	OuiDb[len(OuiDb)-1].VendorPrefix = "1a:2b:3d"
+	ReindexOuiDb()
+	vendor, err := FindVendorByMac("1a:2b:3d:00:00:01")
+	assert.NoError(t, err)
+	assert.Equal(t, "Renumbered", vendor.Vendor)
+	_, err = FindVendorByMac("1a:2b:3c:00:00:01")
+	var noVendor NoVendorError
+	assert.True(t, errors.As(err, &noVendor))
+	assert.Equal(t, "1a:2b:3c:00:00:01", noVendor.Prefix)
+	assert.Contains(t, err.Error(), "1a:2b:3c:00:00:01")
+}
@@ -0,0 +1,209 @@
+package libmacouflage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// Values of /sys/class/net/<if>/addr_assign_type
+const (
+	NET_ADDR_PERM   = 0
+	NET_ADDR_RANDOM = 1
+	NET_ADDR_STOLEN = 2
+	NET_ADDR_SET    = 3
+)
+
+// PermAddrSource tells which mechanism answered a permanent MAC query. The
+// sources are ordered from most to least trustworthy.
+type PermAddrSource int
+
+const (
+	PermAddrNone PermAddrSource = iota
+	// Reported by the kernel as IFLA_PERM_ADDRESS
+	PermAddrNetlink
+	// Reported by the driver through ETHTOOL_GPERMADDR
+	PermAddrEthtool
+	// The current address, sysfs says it was never changed
+	PermAddrSysfs
+	// The address recorded before the first change made by this package
+	PermAddrRecorded
+)
+
+type PermanentMac struct {
+	HardwareAddr net.HardwareAddr
+	Source       PermAddrSource
+}
+
+var sysfsNetDir = "/sys/class/net"
+
+// Original addresses are keyed by namespace and interface index
+type originalMacKey struct {
+	nsID  string
+	index int
+}
+
+var originalMacs = struct {
+	sync.Mutex
+	byLink map[originalMacKey]net.HardwareAddr
+}{byLink: make(map[originalMacKey]net.HardwareAddr)}
+
+func (s PermAddrSource) String() string {
+	switch s {
+	case PermAddrNetlink:
+		return "netlink"
+	case PermAddrEthtool:
+		return "ethtool"
+	case PermAddrSysfs:
+		return "sysfs"
+	case PermAddrRecorded:
+		return "recorded"
+	}
+	return "none"
+}
+
+// PermAddrBackend is implemented by backends that find permanent addresses
+// the link does not carry on their own, instead of ethtool and sysfs.
+type PermAddrBackend interface {
+	PermanentHardwareAddr(link Link) (PermanentMac, error)
+}
+
+// GetPermanentMacInfo tries IFLA_PERM_ADDRESS, then ETHTOOL_GPERMADDR, then
+// sysfs addr_assign_type together with the recorded original address.
+func GetPermanentMacInfo(name string) (perm PermanentMac, err error) {
+	return defaultHandle().GetPermanentMacInfo(name)
+}
+
+func (h *Handle) GetPermanentMacInfo(name string) (perm PermanentMac, err error) {
+	link, err := h.checkInterface(name)
+	if err != nil {
+		return
+	}
+	if !isZeroMac(link.PermHardwareAddr) {
+		perm = PermanentMac{link.PermHardwareAddr, PermAddrNetlink}
+		return
+	}
+	var driverErr error
+	if permBackend, ok := h.backend.(PermAddrBackend); ok {
+		perm, driverErr = permBackend.PermanentHardwareAddr(link)
+		if driverErr == nil {
+			return
+		}
+	} else {
+		perm, driverErr = h.driverPermanentMac(link)
+		if driverErr == nil {
+			return
+		}
+	}
+	if mac, ok := h.lookupOriginalMac(link); ok {
+		perm = PermanentMac{mac, PermAddrRecorded}
+		return
+	}
+	perm = PermanentMac{}
+	err = LinkError{ErrPermAddrUnsupported, "permaddr", name, driverErr}
+	return
+}
+
+// driverPermanentMac asks ethtool, then checks in sysfs that the current
+// address is the original one
+func (h *Handle) driverPermanentMac(link Link) (perm PermanentMac, err error) {
+	mac, err := getEthtoolPermanentMac(h.ns, link.Name)
+	if err == nil && !isZeroMac(mac) {
+		perm = PermanentMac{mac, PermAddrEthtool}
+		return
+	}
+	if err == nil {
+		err = fmt.Errorf("ethtool: all-zero address")
+	} else {
+		err = fmt.Errorf("ethtool: %v", err)
+	}
+	// sysfs shows the namespace it was mounted from, only trust it for ours
+	assignType, sysfsErr := getAddrAssignType(link.Name)
+	if h.ns.IsCurrent() && sysfsErr == nil && (assignType == NET_ADDR_PERM || assignType == NET_ADDR_RANDOM) {
+		perm = PermanentMac{link.HardwareAddr, PermAddrSysfs}
+		err = nil
+	}
+	return
+}
+
+// RecordOriginalMac remembers mac as the original address of the named
+// interface, for use when neither the kernel nor the driver can report it.
+// SetMac records the current address on its own before the first change.
+func RecordOriginalMac(name string, mac net.HardwareAddr) (err error) {
+	return defaultHandle().RecordOriginalMac(name, mac)
+}
+
+func (h *Handle) RecordOriginalMac(name string, mac net.HardwareAddr) (err error) {
+	link, err := h.lookup(name)
+	if err != nil {
+		return
+	}
+	originalMacs.Lock()
+	defer originalMacs.Unlock()
+	originalMacs.byLink[originalMacKey{h.nsID, link.Index}] = append(net.HardwareAddr(nil), mac...)
+	return
+}
+
+func (h *Handle) recordOriginalMac(link Link) {
+	originalMacs.Lock()
+	defer originalMacs.Unlock()
+	key := originalMacKey{h.nsID, link.Index}
+	if _, ok := originalMacs.byLink[key]; !ok {
+		originalMacs.byLink[key] = append(net.HardwareAddr(nil), link.HardwareAddr...)
+	}
+}
+
+func (h *Handle) lookupOriginalMac(link Link) (mac net.HardwareAddr, ok bool) {
+	originalMacs.Lock()
+	defer originalMacs.Unlock()
+	mac, ok = originalMacs.byLink[originalMacKey{h.nsID, link.Index}]
+	return
+}
+
+func getEthtoolPermanentMac(ns NetNS, name string) (mac net.HardwareAddr, err error) {
+	sockfd, err := openIoctlSocket(ns)
+	if err != nil {
+		return
+	}
+	defer syscall.Close(sockfd)
+	var epa EthtoolPermAddr
+	epa.cmd = ETHTOOL_GPERMADDR
+	epa.size = MAX_ADDR_LEN
+	var ifr ifreqData
+	copy(ifr.name[:IFNAMSIZ-1], []byte(name))
+	ifr.data = unsafe.Pointer(&epa)
+	err = ioctl(sockfd, SIOCETHTOOL, unsafe.Pointer(&ifr))
+	if err != nil {
+		return
+	}
+	if epa.size > MAX_ADDR_LEN {
+		err = fmt.Errorf("Invalid permanent address size: %d", epa.size)
+		return
+	}
+	mac = append(net.HardwareAddr(nil), epa.data[:epa.size]...)
+	return
+}
+
+func getAddrAssignType(name string) (assignType int, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(sysfsNetDir, name, "addr_assign_type"))
+	if err != nil {
+		return
+	}
+	assignType, err = strconv.Atoi(strings.TrimSpace(string(data)))
+	return
+}
+
+func isZeroMac(mac net.HardwareAddr) bool {
+	for _, b := range mac {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package libmacouflage
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubBackend struct {
+	link Link
+}
+
+func (b stubBackend) Links() ([]Link, error) {
+	return []Link{b.link}, nil
+}
+
+func (b stubBackend) LinkByName(name string) (Link, error) {
+	return b.link, nil
+}
+
+func (b stubBackend) LinkByIndex(index int) (Link, error) {
+	return b.link, nil
+}
+
+func (b stubBackend) SetHardwareAddr(link Link, mac net.HardwareAddr) error {
+	return nil
+}
+
+func (b stubBackend) SetAdminState(link Link, up bool) error {
+	return nil
+}
+
+func withSysfs(t *testing.T, name string, assignType string) func() {
+	dir, err := ioutil.TempDir("", "sysfs")
+	assert.NoError(t, err)
+	os.MkdirAll(filepath.Join(dir, name), 0755)
+	ioutil.WriteFile(filepath.Join(dir, name, "addr_assign_type"), []byte(assignType+"\n"), 0644)
+	oldDir := sysfsNetDir
+	sysfsNetDir = dir
+	return func() {
+		sysfsNetDir = oldDir
+		os.RemoveAll(dir)
+	}
+}
+
+func withBackend(backend LinkBackend) func() {
+	oldBackend := GetLinkBackend()
+	SetLinkBackend(backend)
+	return func() {
+		SetLinkBackend(oldBackend)
+	}
+}
+
+func Test_GetPermanentMacInfo_1(t *testing.T) {
+	_, err := GetPermanentMacInfo("badinterface")
+	assert.Error(t, err, "Function failed to generate error for bad interface")
+}
+
+func Test_GetPermanentMacInfo_2(t *testing.T) {
+	perm, _ := net.ParseMAC("00:11:22:33:44:55")
+	current, _ := net.ParseMAC("02:00:00:00:00:01")
+	defer withBackend(stubBackend{Link{Index: 1000, Name: "stub0", HardwareAddr: current, PermHardwareAddr: perm}})()
+	result, err := GetPermanentMacInfo("stub0")
+	assert.NoError(t, err)
+	assert.Equal(t, PermAddrNetlink, result.Source)
+	assert.Equal(t, perm, result.HardwareAddr)
+}
+
+func Test_GetPermanentMacInfo_3(t *testing.T) {
+	current, _ := net.ParseMAC("02:00:00:00:00:01")
+	defer withBackend(stubBackend{Link{Index: 1001, Name: "stub1", HardwareAddr: current}})()
+	defer withSysfs(t, "stub1", "1")()
+	result, err := GetPermanentMacInfo("stub1")
+	assert.NoError(t, err)
+	assert.Equal(t, PermAddrSysfs, result.Source)
+	assert.Equal(t, current, result.HardwareAddr)
+}
+
+func Test_GetPermanentMacInfo_4(t *testing.T) {
+	original, _ := net.ParseMAC("00:11:22:33:44:55")
+	current, _ := net.ParseMAC("02:00:00:00:00:01")
+	defer withBackend(stubBackend{Link{Index: 1002, Name: "stub2", HardwareAddr: current}})()
+	defer withSysfs(t, "stub2", "3")()
+	key := originalMacKey{defaultHandle().nsID, 1002}
+	t.Cleanup(func() {
+		originalMacs.Lock()
+		defer originalMacs.Unlock()
+		delete(originalMacs.byLink, key)
+	})
+	_, err := GetPermanentMacInfo("stub2")
+	assert.Error(t, err, "Function failed to generate error without a recorded address")
+	err = RecordOriginalMac("stub2", original)
+	assert.NoError(t, err)
+	result, err := GetPermanentMacInfo("stub2")
+	assert.NoError(t, err)
+	assert.Equal(t, PermAddrRecorded, result.Source)
+	assert.Equal(t, original, result.HardwareAddr)
+}
+
+func Test_PermAddrSource_String_1(t *testing.T) {
+	assert.Equal(t, "ethtool", PermAddrEthtool.String())
+	assert.Equal(t, "none", PermAddrNone.String())
+}
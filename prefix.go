@@ -0,0 +1,92 @@
+package libmacouflage
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Prefix lengths of the IEEE assignments
+const (
+	PrefixBitsMAL = 24
+	PrefixBitsMAM = 28
+	PrefixBitsMAS = 36
+)
+
+// ParsePrefix parses a vendor prefix, bytes in hex separated by colons with
+// an optional length in bits, such as "00:1b:21" or "70:b3:d5:0a:b0/36".
+// Without a length every byte counts. The bits beyond the length are cleared.
+func ParsePrefix(s string) (prefix net.HardwareAddr, bits int, err error) {
+	hexBytes, length, found := strings.Cut(s, "/")
+	for _, part := range strings.Split(hexBytes, ":") {
+		var b []byte
+		b, err = hex.DecodeString(part)
+		if err != nil || len(b) != 1 {
+			err = InvalidMacError{s, fmt.Errorf("Invalid vendor prefix")}
+			return
+		}
+		prefix = append(prefix, b[0])
+	}
+	bits = len(prefix) * 8
+	if found {
+		bits, err = strconv.Atoi(length)
+		if err != nil || bits <= (len(prefix)-1)*8 || bits > len(prefix)*8 {
+			err = InvalidMacError{s, fmt.Errorf("Invalid vendor prefix length")}
+			return
+		}
+	}
+	if bits%8 != 0 {
+		prefix[len(prefix)-1] &= 0xff << (8 - bits%8)
+	}
+	return
+}
+
+// Prefix returns the prefix of the entry and its length in bits, 24 for the
+// MA-L blocks.
+func (o Oui) Prefix() (prefix net.HardwareAddr, bits int, err error) {
+	return ParsePrefix(o.VendorPrefix)
+}
+
+// RandomizeMacPrefix randomizes an EUI-48 or EUI-64 in place but for its
+// first bits. As with RandomizeMac the address is unicast and its U/L bit
+// follows bia, even when they are part of the prefix.
+func RandomizeMacPrefix(macbytes net.HardwareAddr, bits int, bia bool) (mac net.HardwareAddr, err error) {
+	if len(macbytes) != 6 && len(macbytes) != 8 {
+		err = InvalidMacError{macbytes.String(), fmt.Errorf("Invalid size for macbytes byte array: %d", len(macbytes))}
+		return
+	}
+	if bits < 0 || bits >= len(macbytes)*8 {
+		err = fmt.Errorf("%w: invalid prefix length %d", ErrInvalidArgument, bits)
+		return
+	}
+	random := append(net.HardwareAddr(nil), macbytes...)
+	start := bits / 8
+	err = readRandom(random[start:])
+	if err != nil {
+		return
+	}
+	applyPrefix(random, macbytes, bits)
+	copy(macbytes, random)
+	macbytes[0] &^= 1
+	if bia {
+		macbytes[0] &^= 2
+	} else {
+		macbytes[0] |= 2
+	}
+	mac = macbytes
+	return
+}
+
+// applyPrefix copies the first bits of prefix into mac
+func applyPrefix(mac net.HardwareAddr, prefix net.HardwareAddr, bits int) {
+	for i := 0; i < len(mac) && bits > 0; i++ {
+		mask := byte(0xff)
+		if bits < 8 {
+			mask <<= 8 - bits
+		}
+		mac[i] = mac[i]&^mask | prefix[i]&mask
+		bits -= 8
+	}
+}
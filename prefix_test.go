@@ -0,0 +1,62 @@
+package libmacouflage
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParsePrefix_1(t *testing.T) {
+	prefix, bits, err := ParsePrefix("70:B3:D5:0A:BF/36")
+	assert.NoError(t, err)
+	assert.Equal(t, PrefixBitsMAS, bits)
+	assert.Equal(t, net.HardwareAddr{0x70, 0xb3, 0xd5, 0x0a, 0xb0}, prefix)
+	_, bits, err = Oui{VendorPrefix: "00:1b:21"}.Prefix()
+	assert.NoError(t, err)
+	assert.Equal(t, PrefixBitsMAL, bits)
+	for _, s := range []string{"", "00:1b:2", "00:1b:21/16", "00:1b:21/25", "00:1b:21:x0", "00:1b:21/y"} {
+		_, _, err = ParsePrefix(s)
+		assert.True(t, errors.Is(err, ErrInvalidMac), s)
+	}
+}
+
+func Test_RandomizeMacPrefix_1(t *testing.T) {
+	prefix, _, _ := ParsePrefix("70:b3:d5:0a:b0/36")
+	for i := 0; i < 50; i++ {
+		mac := net.HardwareAddr{0x70, 0xb3, 0xd5, 0x0a, 0xbf, 0xff}
+		_, err := RandomizeMacPrefix(mac, PrefixBitsMAS, true)
+		assert.NoError(t, err)
+		assert.Equal(t, prefix[:4], mac[:4])
+		assert.Equal(t, byte(0xb0), mac[4]&0xf0)
+		mac = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+		_, err = RandomizeMacPrefix(mac, 4, false)
+		assert.NoError(t, err)
+		assert.Equal(t, byte(0xf2), mac[0]&0xf3)
+	}
+	_, err := RandomizeMacPrefix(make(net.HardwareAddr, 6), 48, true)
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+	_, err = RandomizeMacPrefix(make(net.HardwareAddr, 6), -1, true)
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+	_, err = RandomizeMacPrefix(make(net.HardwareAddr, 5), 0, true)
+	assert.True(t, errors.Is(err, ErrInvalidMac))
+	_, err = RandomizeMac(make(net.HardwareAddr, 6), 2, true)
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+	_, err = RandomizeMac(make(net.HardwareAddr, 7), 0, true)
+	assert.True(t, errors.Is(err, ErrInvalidMac))
+}
+
+func Test_WithVendor_1(t *testing.T) {
+	format, _ := AddressFormatOf(Link{HardwareAddr: make(net.HardwareAddr, 6)})
+	mac, err := format.WithVendor(make(net.HardwareAddr, 6), "00:55:da:50/28")
+	assert.NoError(t, err)
+	assert.Equal(t, "00:55:da:5", mac.String()[:10])
+	mac, err = format.WithVendor(make(net.HardwareAddr, 6), "00:1b:21")
+	assert.NoError(t, err)
+	assert.Equal(t, "00:1b:21", mac.String()[:8])
+	mac, err = spoofConfig{}.generate(RandomStrategy(false), format, make(net.HardwareAddr, 6), nil,
+		[]Oui{{VendorPrefix: "70:b3:d5:0a:b0/36"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "70:b3:d5:0a:b", mac.String()[:13])
+}
@@ -0,0 +1,111 @@
+package libmacouflage
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"sync"
+)
+
+// randomSource is what every address and vendor choice is read from
+var randomSource = struct {
+	sync.Mutex
+	r io.Reader
+}{r: rand.Reader}
+
+// SetRandomSource makes the generation of addresses and the choice of vendors
+// read from r, crypto/rand.Reader when nil, and returns the previous source.
+// r is only read under a lock, it need not be safe for concurrent use.
+func SetRandomSource(r io.Reader) (previous io.Reader) {
+	if r == nil {
+		r = rand.Reader
+	}
+	randomSource.Lock()
+	defer randomSource.Unlock()
+	previous, randomSource.r = randomSource.r, r
+	return
+}
+
+// NewSeededSource returns a predictable source for SetRandomSource, which
+// makes the generated addresses reproducible in tests. It must not be used to
+// hide an interface.
+func NewSeededSource(seed int64) io.Reader {
+	return mathrand.New(mathrand.NewSource(seed))
+}
+
+// readRandom fills buf from the random source
+func readRandom(buf []byte) (err error) {
+	randomSource.Lock()
+	defer randomSource.Unlock()
+	_, err = io.ReadFull(randomSource.r, buf)
+	if err != nil {
+		err = fmt.Errorf("%w: cannot read random bytes: %w", ErrRandomSource, err)
+	}
+	return
+}
+
+// RandomIntn returns a uniformly distributed number in [0, max) from the
+// random source. Draws beyond the largest multiple of max are discarded, so
+// that no number is more likely than another.
+func RandomIntn(max int) (result int, err error) {
+	if max <= 0 {
+		err = fmt.Errorf("%w: invalid maximum %d", ErrInvalidArgument, max)
+		return
+	}
+	v, err := randomUint64n(uint64(max))
+	result = int(v)
+	return
+}
+
+// randomUint64n returns a uniformly distributed number in [0, n) from the
+// random source, n must not be 0
+func randomUint64n(n uint64) (result uint64, err error) {
+	limit := math.MaxUint64 - math.MaxUint64%n
+	buf := make([]byte, 8)
+	for {
+		err = readRandom(buf)
+		if err != nil {
+			return
+		}
+		v := binary.BigEndian.Uint64(buf)
+		if v < limit {
+			result = v % n
+			return
+		}
+	}
+}
+
+// RandomInt returns a number in [0, max) from the random source, from
+// math/rand when the source fails, and 0 when max is not positive.
+//
+// Deprecated: RandomInt hides the failures of the random source, use
+// RandomIntn.
+func RandomInt(max int) (result int) {
+	if max <= 0 {
+		return
+	}
+	result, err := RandomIntn(max)
+	if err != nil {
+		result = mathrand.Intn(max)
+	}
+	return
+}
+
+// randomVendor returns an entry of vendors picked from the random source,
+// never a Company ID
+func randomVendor(vendors []Oui) (vendor Oui, err error) {
+	vendors = addressBlocks(vendors)
+	if len(vendors) == 0 {
+		err = NoVendorError{"No vendor prefix to draw an address from", ""}
+		return
+	}
+	n, err := RandomIntn(len(vendors))
+	if err != nil {
+		return
+	}
+	vendor = vendors[n]
+	return
+}
@@ -0,0 +1,60 @@
+package libmacouflage
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failingReader struct{}
+
+var errFailingReader = errors.New("entropy exhausted")
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errFailingReader
+}
+
+func Test_RandomSource_1(t *testing.T) {
+	defer SetRandomSource(SetRandomSource(NewSeededSource(42)))
+	first, err := RandomizeMac(make(net.HardwareAddr, 6), 0, false)
+	assert.NoError(t, err)
+	SetRandomSource(NewSeededSource(42))
+	second, err := RandomizeMac(make(net.HardwareAddr, 6), 0, false)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, byte(2), first[0]&3)
+}
+
+func Test_RandomSource_2(t *testing.T) {
+	defer SetRandomSource(SetRandomSource(failingReader{}))
+	mac := net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55}
+	_, err := RandomizeMac(mac, 3, true)
+	assert.Error(t, err)
+	assert.Equal(t, "00:11:22:33:44:55", mac.String())
+	_, err = RandomIntn(10)
+	assert.True(t, errors.Is(err, ErrRandomSource))
+	assert.True(t, errors.Is(err, errFailingReader))
+	_, err = generatePopular(mac, nil)
+	assert.Error(t, err)
+	assert.NotPanics(t, func() {
+		n := RandomInt(10)
+		assert.True(t, n >= 0 && n < 10)
+		assert.Equal(t, 0, RandomInt(0))
+	})
+}
+
+func Test_RandomIntn_1(t *testing.T) {
+	_, err := RandomIntn(0)
+	assert.True(t, errors.Is(err, ErrInvalidArgument))
+	counts := make([]int, 3)
+	for i := 0; i < 3000; i++ {
+		n, err := RandomIntn(3)
+		assert.NoError(t, err)
+		counts[n]++
+	}
+	for _, count := range counts {
+		assert.InDelta(t, 1000, count, 150)
+	}
+}
@@ -0,0 +1,134 @@
+package libmacouflage
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+)
+
+// Names of the spoofing modes, as in the long options of macchanger
+const (
+	ModeSpecific = "mac"
+	ModeRandom   = "random"
+	ModeEnding   = "ending"
+	ModeAnother  = "another"
+	ModeAny      = "any"
+	ModePopular  = "popular"
+	ModeStable   = "stable"
+	ModeEpoch    = "epoch"
+	ModeAAI      = "aai"
+	ModeSAI      = "sai"
+	ModeELI      = "eli"
+	ModeReserved = "slap-reserved"
+)
+
+// Random addresses the driver refuses are replaced by new ones this many
+// times in all
+const spoofAttempts = 3
+
+// SpoofResult describes a MAC change made by Spoof or the SpoofMac functions.
+// Vendor and Device are the entries of OuiDb matching the new MAC, if any.
+type SpoofResult struct {
+	Interface    string
+	Mode         string
+	OldMac       net.HardwareAddr
+	NewMac       net.HardwareAddr
+	PermanentMac net.HardwareAddr
+	Changed      bool
+	// DryRun is set when NewMac was only generated
+	DryRun   bool
+	Vendor   *Oui
+	Device   *Device
+	Attempts int
+	Started  time.Time
+	Duration time.Duration
+}
+
+// spoofResultJSON is SpoofResult with the addresses and the duration as
+// text
+type spoofResultJSON struct {
+	Interface    string    `json:"interface"`
+	Mode         string    `json:"mode"`
+	OldMac       string    `json:"old_mac"`
+	NewMac       string    `json:"new_mac"`
+	PermanentMac string    `json:"permanent_mac,omitempty"`
+	Changed      bool      `json:"changed"`
+	DryRun       bool      `json:"dry_run,omitempty"`
+	Vendor       *Oui      `json:"vendor,omitempty"`
+	Device       *Device   `json:"device,omitempty"`
+	Attempts     int       `json:"attempts"`
+	Started      time.Time `json:"started"`
+	Duration     string    `json:"duration"`
+}
+
+func (r SpoofResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(spoofResultJSON{
+		Interface:    r.Interface,
+		Mode:         r.Mode,
+		OldMac:       r.OldMac.String(),
+		NewMac:       r.NewMac.String(),
+		PermanentMac: r.PermanentMac.String(),
+		Changed:      r.Changed,
+		DryRun:       r.DryRun,
+		Vendor:       r.Vendor,
+		Device:       r.Device,
+		Attempts:     r.Attempts,
+		Started:      r.Started,
+		Duration:     r.Duration.String(),
+	})
+}
+
+func (r *SpoofResult) UnmarshalJSON(data []byte) (err error) {
+	var j spoofResultJSON
+	err = json.Unmarshal(data, &j)
+	if err != nil {
+		return
+	}
+	*r = SpoofResult{
+		Interface: j.Interface,
+		Mode:      j.Mode,
+		Changed:   j.Changed,
+		DryRun:    j.DryRun,
+		Vendor:    j.Vendor,
+		Device:    j.Device,
+		Attempts:  j.Attempts,
+		Started:   j.Started,
+	}
+	for _, field := range []struct {
+		text string
+		mac  *net.HardwareAddr
+	}{{j.OldMac, &r.OldMac}, {j.NewMac, &r.NewMac}, {j.PermanentMac, &r.PermanentMac}} {
+		if field.text == "" {
+			continue
+		}
+		*field.mac, err = net.ParseMAC(field.text)
+		if err != nil {
+			return
+		}
+	}
+	if j.Duration != "" {
+		r.Duration, err = time.ParseDuration(j.Duration)
+	}
+	return
+}
+
+func (r *SpoofResult) setVendor(vendor *Oui) {
+	r.Vendor = vendor
+	r.Device = nil
+	if vendor != nil && len(vendor.Devices) > 0 {
+		r.Device = &vendor.Devices[0]
+	}
+}
+
+// vendorOf returns the entry of OuiDb matching the EUI of mac, if any
+func vendorOf(format AddressFormat, mac net.HardwareAddr) *Oui {
+	eui := format.EUI(mac)
+	if eui == nil {
+		return nil
+	}
+	vendor, err := FindVendorByMac(eui.String())
+	if err != nil {
+		return nil
+	}
+	return &vendor
+}
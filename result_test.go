@@ -0,0 +1,58 @@
+package libmacouflage
+
+import (
+	"encoding/json"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SpoofResult_1(t *testing.T) {
+	backend := newTestFakeBackend()
+	h := NewHandle(backend)
+	result, err := h.SpoofMacPopular("fake0")
+	assert.NoError(t, err)
+	assert.Equal(t, "fake0", result.Interface)
+	assert.Equal(t, 1, result.Attempts)
+	assert.True(t, result.Vendor.Popular)
+	assert.Equal(t, result.Vendor.Devices[0], *result.Device)
+	iface, _ := backend.Interface("fake0")
+	assert.Equal(t, iface.HardwareAddr, result.NewMac)
+	assert.Equal(t, iface.PermHardwareAddr, result.PermanentMac)
+	assert.NotEqual(t, result.OldMac, result.NewMac)
+}
+
+func Test_SpoofResult_2(t *testing.T) {
+	backend := newTestFakeBackend()
+	refused := 0
+	backend.AddDriverRule("e1000e", func(iface FakeInterface, mac net.HardwareAddr) error {
+		if refused < 2 {
+			refused++
+			return syscall.EADDRNOTAVAIL
+		}
+		return nil
+	})
+	result, err := NewHandle(backend).SpoofMacRandom("fake0", false)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Attempts)
+	assert.Nil(t, result.Vendor)
+}
+
+func Test_SpoofResult_3(t *testing.T) {
+	result, err := NewHandle(newTestFakeBackend()).SpoofMacAnyDeviceType("fake0")
+	assert.NoError(t, err)
+	data, err := json.Marshal(result)
+	assert.NoError(t, err)
+	var fields map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, result.NewMac.String(), fields["new_mac"])
+	assert.Equal(t, ModeAny, fields["mode"])
+	var decoded SpoofResult
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, result.NewMac, decoded.NewMac)
+	assert.Equal(t, result.Duration, decoded.Duration)
+	assert.Equal(t, result.Vendor.VendorPrefix, decoded.Vendor.VendorPrefix)
+	assert.True(t, result.Started.Equal(decoded.Started))
+}
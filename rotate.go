@@ -0,0 +1,167 @@
+package libmacouflage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// A rotation held back by the carrier is tried again after this long
+const quietPollInterval = 5 * time.Second
+
+// Rotation is the schedule of an interface. Each rotation is due Interval
+// plus a random part of Jitter after the previous one.
+type Rotation struct {
+	Interface string
+	Strategy  Strategy
+	Options   []SpoofOption
+	Interval  time.Duration
+	Jitter    time.Duration
+	// QuietOnly holds a due rotation back until the link has no carrier, so
+	// that established connections are not cut.
+	QuietOnly bool
+	// Immediate rotates once when the rotator starts.
+	Immediate bool
+}
+
+// RotationEvent reports a rotation, successful or not.
+type RotationEvent struct {
+	Interface string
+	Time      time.Time
+	Result    SpoofResult
+	Err       error
+}
+
+// Rotator changes the MACs of interfaces on a schedule until its context is
+// done.
+type Rotator struct {
+	handle    *Handle
+	rotations []Rotation
+	// Clock is SystemClock unless set
+	Clock Clock
+	// RevertOnStop sets the permanent MACs of the rotated interfaces
+	// again when Run returns.
+	RevertOnStop bool
+	// OnRotate is called after each rotation from the goroutine of Run.
+	OnRotate func(RotationEvent)
+}
+
+func NewRotator(h *Handle, rotations ...Rotation) *Rotator {
+	return &Rotator{handle: h, rotations: rotations, Clock: SystemClock}
+}
+
+// Run rotates until ctx is done or the jitter cannot be drawn. Failed
+// rotations are reported to OnRotate and tried again at the next one. The
+// error of the revert on stop is joined to that of Run.
+func (r *Rotator) Run(ctx context.Context) (err error) {
+	for _, rotation := range r.rotations {
+		if rotation.Interval <= 0 || rotation.Jitter < 0 || rotation.Strategy == nil {
+			err = fmt.Errorf("Invalid rotation of %s: needs a strategy and a positive interval", rotation.Interface)
+			return
+		}
+	}
+	if len(r.rotations) == 0 {
+		<-ctx.Done()
+		return
+	}
+	clock := r.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	now := clock.Now()
+	next := make([]time.Time, len(r.rotations))
+	for i, rotation := range r.rotations {
+		next[i] = now
+		if !rotation.Immediate {
+			next[i], err = rotation.next(now)
+			if err != nil {
+				return
+			}
+		}
+	}
+	rotated := make(map[string]bool)
+	defer func() {
+		if r.RevertOnStop {
+			err = errors.Join(err, r.revert(rotated))
+		}
+	}()
+	for {
+		due := next[0]
+		for _, t := range next[1:] {
+			if t.Before(due) {
+				due = t
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(due.Sub(clock.Now())):
+		}
+		now = clock.Now()
+		for i, rotation := range r.rotations {
+			if next[i].After(now) {
+				continue
+			}
+			if rotation.QuietOnly && r.hasCarrier(rotation.Interface) {
+				next[i] = now.Add(quietPollInterval)
+				continue
+			}
+			event := RotationEvent{Interface: rotation.Interface, Time: now}
+			event.Result, event.Err = r.handle.Spoof(ctx, rotation.Interface, rotation.Strategy, rotation.Options...)
+			if event.Err == nil {
+				rotated[rotation.Interface] = true
+			}
+			if r.OnRotate != nil {
+				r.OnRotate(event)
+			}
+			next[i], err = rotation.next(now)
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// next returns when the rotation after one at now is due
+func (rotation Rotation) next(now time.Time) (due time.Time, err error) {
+	due = now.Add(rotation.Interval)
+	if rotation.Jitter == 0 {
+		return
+	}
+	// A duration does not fit an int on 32 bit architectures
+	jitter, err := randomUint64n(uint64(rotation.Jitter))
+	due = due.Add(time.Duration(jitter))
+	return
+}
+
+// hasCarrier reports whether the link called name has a carrier, a link that
+// cannot be found has none
+func (r *Rotator) hasCarrier(name string) bool {
+	link, err := r.handle.lookup(name)
+	return err == nil && link.Carrier
+}
+
+// revert sets the permanent MACs of the rotated interfaces the way they were
+// rotated, with the same SetMacOptions
+func (r *Rotator) revert(rotated map[string]bool) (err error) {
+	var errs []error
+	for _, rotation := range r.rotations {
+		if !rotated[rotation.Interface] {
+			continue
+		}
+		delete(rotated, rotation.Interface)
+		var config spoofConfig
+		for _, opt := range rotation.Options {
+			opt(&config)
+		}
+		mac, perr := r.handle.GetPermanentMac(rotation.Interface)
+		if perr != nil {
+			errs = append(errs, perr)
+			continue
+		}
+		_, serr := r.handle.setMacWithOptions(context.Background(), rotation.Interface, mac.String(), config.setOptions)
+		errs = append(errs, serr)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,102 @@
+package libmacouflage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runRotator runs r until stop is called, which returns the result of Run
+func runRotator(r *Rotator) (events chan RotationEvent, stop func() error) {
+	events = make(chan RotationEvent, 16)
+	r.OnRotate = func(event RotationEvent) {
+		events <- event
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- r.Run(ctx)
+	}()
+	return events, func() error {
+		cancel()
+		return <-done
+	}
+}
+
+func Test_Rotator_1(t *testing.T) {
+	backend := newTestFakeBackend()
+	clock := NewFakeClock(time.Unix(0, 0))
+	r := NewRotator(NewHandle(backend), Rotation{Interface: "fake0",
+		Strategy: RandomStrategy(false), Interval: time.Minute, Jitter: time.Second})
+	r.Clock = clock
+	r.RevertOnStop = true
+	events, stop := runRotator(r)
+	for i := 0; i < 3; i++ {
+		clock.BlockUntilWaiters(1)
+		clock.Advance(time.Minute - time.Millisecond)
+		assert.Empty(t, events)
+		clock.Advance(time.Second + time.Millisecond)
+		event := <-events
+		assert.NoError(t, event.Err)
+		assert.Equal(t, "fake0", event.Interface)
+	}
+	assert.Len(t, backend.History("fake0"), 3)
+	assert.NoError(t, stop())
+	iface, _ := backend.Interface("fake0")
+	assert.Equal(t, iface.PermHardwareAddr, iface.HardwareAddr)
+}
+
+func Test_Rotator_2(t *testing.T) {
+	backend := newTestFakeBackend()
+	iface, _ := backend.Interface("fake0")
+	iface.Up = true
+	backend.AddInterface(iface)
+	clock := NewFakeClock(time.Unix(0, 0))
+	r := NewRotator(NewHandle(backend), Rotation{Interface: "fake0",
+		Strategy: RandomStrategy(false), Interval: time.Hour, QuietOnly: true, Immediate: true})
+	r.Clock = clock
+	events, stop := runRotator(r)
+	clock.BlockUntilWaiters(1)
+	clock.Advance(quietPollInterval)
+	clock.BlockUntilWaiters(1)
+	assert.Empty(t, events)
+	backend.SetCarrier("fake0", false)
+	clock.Advance(quietPollInterval)
+	event := <-events
+	assert.NoError(t, event.Err)
+	assert.Equal(t, time.Unix(0, 0).Add(2*quietPollInterval), event.Time)
+	assert.NoError(t, stop())
+	iface, _ = backend.Interface("fake0")
+	assert.Equal(t, event.Result.NewMac, iface.HardwareAddr)
+}
+
+func Test_Rotator_3(t *testing.T) {
+	r := NewRotator(NewHandle(newTestFakeBackend()), Rotation{Interface: "fake0", Interval: time.Minute})
+	assert.Error(t, r.Run(context.Background()))
+}
+
+func Test_Rotator_4(t *testing.T) {
+	r := NewRotator(NewHandle(newTestFakeBackend()), Rotation{Interface: "fake0",
+		Strategy: RandomStrategy(false), Interval: time.Minute, Jitter: time.Second, Immediate: true})
+	r.Clock = nil
+	r.RevertOnStop = true
+	previous := SetRandomSource(iotest.ErrReader(errors.New("no entropy")))
+	defer SetRandomSource(previous)
+	assert.Error(t, r.Run(context.Background()))
+}
+
+func Test_Rotation_Next_1(t *testing.T) {
+	rotation := Rotation{Interval: time.Hour, Jitter: 10 * time.Minute}
+	now := time.Unix(0, 0)
+	for i := 0; i < 100; i++ {
+		due, err := rotation.next(now)
+		assert.NoError(t, err)
+		assert.False(t, due.Before(now.Add(time.Hour)))
+		assert.True(t, due.Before(now.Add(time.Hour+10*time.Minute)))
+	}
+	assert.True(t, int64(rotation.Jitter) > 1<<31)
+}
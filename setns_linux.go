@@ -0,0 +1,7 @@
+//go:build linux && !amd64 && !386
+
+package libmacouflage
+
+import "syscall"
+
+const SYS_SETNS = syscall.SYS_SETNS
@@ -0,0 +1,4 @@
+package libmacouflage
+
+// The frozen syscall package lacks SYS_SETNS on this architecture
+const SYS_SETNS = 346
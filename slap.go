@@ -0,0 +1,144 @@
+package libmacouflage
+
+import (
+	"fmt"
+	"net"
+)
+
+// SLAPQuadrant is the IEEE 802c Structured Local Address Plan quadrant of a
+// locally administered address, told by the Y and Z bits of its first byte.
+type SLAPQuadrant int
+
+const (
+	// SLAPNone is the quadrant of universally administered addresses
+	SLAPNone SLAPQuadrant = iota
+	// SLAPAAI addresses are Administratively Assigned Identifiers, x2
+	SLAPAAI
+	// SLAPELI addresses are Extended Local Identifiers under a CID, xA
+	SLAPELI
+	// SLAPSAI addresses are Standard Assigned Identifiers, xE
+	SLAPSAI
+	// SLAPReserved addresses are reserved for future use, x6
+	SLAPReserved
+)
+
+// The low four bits of the first byte in each quadrant
+var slapNibbles = map[SLAPQuadrant]byte{
+	SLAPAAI:      0x2,
+	SLAPELI:      0xa,
+	SLAPSAI:      0xe,
+	SLAPReserved: 0x6,
+}
+
+func (q SLAPQuadrant) String() string {
+	switch q {
+	case SLAPNone:
+		return "None"
+	case SLAPAAI:
+		return "AAI"
+	case SLAPELI:
+		return "ELI"
+	case SLAPSAI:
+		return "SAI"
+	case SLAPReserved:
+		return "Reserved"
+	}
+	return fmt.Sprintf("SLAPQuadrant(%d)", int(q))
+}
+
+// ClassifySLAP returns the quadrant of an EUI-48 or EUI-64, SLAPNone when it
+// is universally administered.
+func ClassifySLAP(mac net.HardwareAddr) (q SLAPQuadrant) {
+	if len(mac) == 0 || mac[0]&2 == 0 {
+		return SLAPNone
+	}
+	for quadrant, nibble := range slapNibbles {
+		if mac[0]&0x0e == nibble {
+			return quadrant
+		}
+	}
+	return SLAPNone
+}
+
+// RandomizeMacSLAP randomizes an EUI-48 or EUI-64 in place as a unicast
+// address of the quadrant q. ELI addresses need a CID, see RandomizeMacELI.
+func RandomizeMacSLAP(macbytes net.HardwareAddr, q SLAPQuadrant) (mac net.HardwareAddr, err error) {
+	nibble, ok := slapNibbles[q]
+	if !ok || q == SLAPELI {
+		err = fmt.Errorf("Cannot randomize addresses in the %s quadrant", q)
+		return
+	}
+	random := append(net.HardwareAddr(nil), macbytes...)
+	_, err = RandomizeMacPrefix(random, 0, false)
+	if err != nil {
+		return
+	}
+	random[0] = random[0]&0xf0 | nibble
+	copy(macbytes, random)
+	mac = macbytes
+	return
+}
+
+// RandomizeMacELI randomizes an EUI-48 or EUI-64 in place as an Extended
+// Local Identifier: the 24 bit Company ID cid followed by random bits.
+func RandomizeMacELI(macbytes net.HardwareAddr, cid net.HardwareAddr) (mac net.HardwareAddr, err error) {
+	if len(cid) != 3 || cid[0]&0x0f != slapNibbles[SLAPELI] {
+		err = fmt.Errorf("Invalid CID %s: a CID has 3 bytes, the first one xA", cid)
+		return
+	}
+	random := append(net.HardwareAddr(nil), macbytes...)
+	applyPrefix(random, cid, 24)
+	_, err = RandomizeMacPrefix(random, 24, false)
+	if err != nil {
+		return
+	}
+	copy(macbytes, random)
+	mac = macbytes
+	return
+}
+
+// SLAPStrategy randomizes the whole address in the quadrant q, SLAPAAI,
+// SLAPSAI or SLAPReserved.
+func SLAPStrategy(q SLAPQuadrant) Strategy {
+	names := map[SLAPQuadrant]string{SLAPAAI: ModeAAI, SLAPSAI: ModeSAI, SLAPReserved: ModeReserved}
+	help := fmt.Sprintf("Set random locally administered MAC in the SLAP %s quadrant", q)
+	return NewMode(q.String(), help, "", names[q],
+		func(current net.HardwareAddr, permanent net.HardwareAddr) (mac net.HardwareAddr, err error) {
+			mac, eui, err := slapEUI(current)
+			if err != nil {
+				return
+			}
+			_, err = RandomizeMacSLAP(eui, q)
+			return
+		})
+}
+
+// ELIStrategy randomizes the address as an Extended Local Identifier under
+// the Company ID cid.
+func ELIStrategy(cid net.HardwareAddr) Strategy {
+	cid = append(net.HardwareAddr(nil), cid...)
+	return NewMode("ELI", "Set random MAC under the CID "+cid.String(), "", ModeELI,
+		func(current net.HardwareAddr, permanent net.HardwareAddr) (mac net.HardwareAddr, err error) {
+			mac, eui, err := slapEUI(current)
+			if err != nil {
+				return
+			}
+			_, err = RandomizeMacELI(eui, cid)
+			return
+		})
+}
+
+// slapEUI returns a copy of current and its EUI
+func slapEUI(current net.HardwareAddr) (mac net.HardwareAddr, eui net.HardwareAddr, err error) {
+	format, err := formatOf(current)
+	if err != nil {
+		return
+	}
+	mac = append(net.HardwareAddr(nil), current...)
+	eui = format.EUI(mac)
+	if eui == nil {
+		msg := fmt.Sprintf("Cannot randomize the hardware address of %s interfaces", format.Name)
+		err = UnsupportedAddressError{msg}
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package libmacouflage
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ClassifySLAP_1(t *testing.T) {
+	for mac, q := range map[string]SLAPQuadrant{
+		"00:1b:21:12:34:56": SLAPNone,
+		"02:00:00:00:00:01": SLAPAAI,
+		"03:00:00:00:00:01": SLAPAAI,
+		"1a:2b:3c:00:00:01": SLAPELI,
+		"fe:00:00:00:00:01": SLAPSAI,
+		"06:00:00:00:00:01": SLAPReserved,
+	} {
+		addr, _ := net.ParseMAC(mac)
+		assert.Equal(t, q, ClassifySLAP(addr), mac)
+	}
+	assert.Equal(t, "ELI", SLAPELI.String())
+}
+
+func Test_RandomizeMacSLAP_1(t *testing.T) {
+	for _, q := range []SLAPQuadrant{SLAPAAI, SLAPSAI, SLAPReserved} {
+		mac, err := RandomizeMacSLAP(make(net.HardwareAddr, 6), q)
+		assert.NoError(t, err)
+		assert.Equal(t, q, ClassifySLAP(mac))
+		assert.Equal(t, byte(0), mac[0]&1)
+	}
+	_, err := RandomizeMacSLAP(make(net.HardwareAddr, 6), SLAPELI)
+	assert.Error(t, err)
+	_, err = RandomizeMacSLAP(make(net.HardwareAddr, 6), SLAPNone)
+	assert.Error(t, err)
+	cid := net.HardwareAddr{0x1a, 0x2b, 0x3c}
+	mac, err := RandomizeMacELI(make(net.HardwareAddr, 8), cid)
+	assert.NoError(t, err)
+	assert.Equal(t, cid, mac[:3])
+	assert.Equal(t, SLAPELI, ClassifySLAP(mac))
+	_, err = RandomizeMacELI(make(net.HardwareAddr, 6), net.HardwareAddr{0x00, 0x1b, 0x21})
+	assert.Error(t, err)
+}
+
+func Test_SLAPStrategy_1(t *testing.T) {
+	current, _ := net.ParseMAC("00:1b:21:12:34:56")
+	s, err := LookupStrategy(ModeSAI)
+	assert.NoError(t, err)
+	mac, err := s.Generate(current, current)
+	assert.NoError(t, err)
+	assert.Equal(t, SLAPSAI, ClassifySLAP(mac))
+	mac, err = ELIStrategy(net.HardwareAddr{0x1a, 0x2b, 0x3c}).Generate(current, current)
+	assert.NoError(t, err)
+	assert.Equal(t, "1a:2b:3c", mac.String()[:8])
+	assert.Equal(t, "00:1b:21:12:34:56", current.String())
+}
@@ -0,0 +1,18 @@
+//go:build linux && !386
+
+package libmacouflage
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// getsockopt reads the socket option name at level of fd into val
+func getsockopt(fd int, level int, name int, val unsafe.Pointer, vallen *uint32) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, uintptr(fd), uintptr(level), uintptr(name),
+		uintptr(val), uintptr(unsafe.Pointer(vallen)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package libmacouflage
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// The socket calls of this architecture go through socketcall
+const _SOCKETCALL_GETSOCKOPT = 15
+
+// getsockopt reads the socket option name at level of fd into val
+func getsockopt(fd int, level int, name int, val unsafe.Pointer, vallen *uint32) error {
+	args := [5]uintptr{uintptr(fd), uintptr(level), uintptr(name), uintptr(val), uintptr(unsafe.Pointer(vallen))}
+	_, _, errno := syscall.Syscall(syscall.SYS_SOCKETCALL, _SOCKETCALL_GETSOCKOPT, uintptr(unsafe.Pointer(&args)), 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package libmacouflage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// SpoofOption changes how Spoof generates and sets the new address.
+type SpoofOption func(*spoofConfig)
+
+type spoofConfig struct {
+	bia           *bool
+	deviceType    string
+	vendorKeyword string
+	attempts      int
+	setOptions    SetMacOptions
+	dryRun        bool
+	timeout       time.Duration
+}
+
+// WithBIA makes the new address a burned-in address if bia is set and a
+// locally administered one otherwise. Without it the strategy decides.
+func WithBIA(bia bool) SpoofOption {
+	return func(c *spoofConfig) {
+		c.bia = &bia
+	}
+}
+
+// WithDeviceType replaces the vendor of the new address with a random one
+// from OuiDb whose device type is deviceType, such as "oui_wireless_laptop".
+func WithDeviceType(deviceType string) SpoofOption {
+	return func(c *spoofConfig) {
+		c.deviceType = deviceType
+	}
+}
+
+// WithVendorKeyword replaces the vendor of the new address with a random one
+// from OuiDb whose name contains keyword.
+func WithVendorKeyword(keyword string) SpoofOption {
+	return func(c *spoofConfig) {
+		c.vendorKeyword = keyword
+	}
+}
+
+// WithAttempts sets how many addresses are tried when the driver refuses
+// them, 3 by default and at least 1.
+func WithAttempts(attempts int) SpoofOption {
+	return func(c *spoofConfig) {
+		c.attempts = attempts
+	}
+}
+
+// WithAutoDown brings an up interface down for the change.
+func WithAutoDown() SpoofOption {
+	return func(c *spoofConfig) {
+		c.setOptions.AutoDown = true
+	}
+}
+
+// WithSetMacOptions sets the options of the change itself.
+func WithSetMacOptions(opts SetMacOptions) SpoofOption {
+	return func(c *spoofConfig) {
+		c.setOptions = opts
+	}
+}
+
+// WithDryRun generates the new address without setting it.
+func WithDryRun() SpoofOption {
+	return func(c *spoofConfig) {
+		c.dryRun = true
+	}
+}
+
+// WithTimeout gives up on the change after timeout.
+func WithTimeout(timeout time.Duration) SpoofOption {
+	return func(c *spoofConfig) {
+		c.timeout = timeout
+	}
+}
+
+func Spoof(ctx context.Context, name string, strategy Strategy, opts ...SpoofOption) (result SpoofResult, err error) {
+	return defaultHandle().Spoof(ctx, name, strategy, opts...)
+}
+
+// Spoof sets an address generated by strategy on the interface called name,
+// generating another one when the driver refuses it, then reads back the new
+// and permanent MACs. It gives up when ctx is done, or when the strategy
+// generates the refused address again.
+func (h *Handle) Spoof(ctx context.Context, name string, strategy Strategy, opts ...SpoofOption) (result SpoofResult, err error) {
+	config := spoofConfig{attempts: spoofAttempts}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if config.attempts < 1 {
+		err = fmt.Errorf("%w: at least one attempt is needed, not %d", ErrInvalidArgument, config.attempts)
+		return
+	}
+	if config.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.timeout)
+		defer cancel()
+	}
+	result.Interface = name
+	result.Mode = strategy.Name()
+	result.DryRun = config.dryRun
+	result.Started = time.Now()
+	defer func() {
+		result.Duration = time.Since(result.Started)
+	}()
+	link, format, err := h.addressFormat(name)
+	if err != nil {
+		return
+	}
+	result.OldMac = link.HardwareAddr
+	vendors, err := config.vendors()
+	if err != nil {
+		return
+	}
+	// Strategies that do not need the permanent address must not fail
+	// for want of it
+	permanent, _ := h.GetPermanentMac(name)
+	var rejected net.HardwareAddr
+	for result.Attempts < config.attempts {
+		if cerr := ctx.Err(); cerr != nil {
+			err = cerr
+			return
+		}
+		mac, gerr := config.generate(strategy, format, link.HardwareAddr, permanent, vendors)
+		if gerr != nil {
+			err = gerr
+			return
+		}
+		// The strategy is deterministic, the driver would refuse it again
+		if rejected != nil && CompareMacs(mac, rejected) {
+			break
+		}
+		result.Attempts++
+		if config.dryRun {
+			err = format.Check(mac)
+			if err != nil {
+				return
+			}
+			result.NewMac = mac
+			result.setVendor(vendorOf(format, mac))
+			result.PermanentMac = permanent
+			result.Changed = !CompareMacs(mac, permanent)
+			return
+		}
+		_, err = h.setMacWithOptions(ctx, name, mac.String(), config.setOptions)
+		if !errors.Is(err, ErrAddressRejected) {
+			break
+		}
+		rejected = mac
+	}
+	if err != nil {
+		return
+	}
+	result.NewMac, err = h.GetCurrentMac(name)
+	if err != nil {
+		return
+	}
+	result.setVendor(vendorOf(format, result.NewMac))
+	result.PermanentMac, err = h.GetPermanentMac(name)
+	if err != nil {
+		return
+	}
+	result.Changed = !CompareMacs(result.NewMac, result.PermanentMac)
+	return
+}
+
+// vendors returns the entries of OuiDb matching the vendor constraints, nil
+// when there are none
+func (c spoofConfig) vendors() (vendors []Oui, err error) {
+	if c.deviceType == "" && c.vendorKeyword == "" {
+		return
+	}
+	for _, oui := range OuiDb {
+		if c.deviceType != "" && (len(oui.Devices) == 0 ||
+			!strings.EqualFold(oui.Devices[0].DeviceType, c.deviceType)) {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(oui.Vendor), strings.ToLower(c.vendorKeyword)) {
+			continue
+		}
+		if oui.Assignment == AssignmentCID {
+			continue
+		}
+		vendors = append(vendors, oui)
+	}
+	if len(vendors) == 0 {
+		msg := fmt.Sprintf("No vendor found in OuiDb for device type %q and keyword %q",
+			c.deviceType, c.vendorKeyword)
+		err = NoVendorError{msg, ""}
+	}
+	return
+}
+
+// generate returns the address strategy generates, with the vendor and the
+// burned-in address bit the options ask for
+func (c spoofConfig) generate(strategy Strategy, format AddressFormat, current net.HardwareAddr, permanent net.HardwareAddr, vendors []Oui) (mac net.HardwareAddr, err error) {
+	mac, err = strategy.Generate(append(net.HardwareAddr(nil), current...), permanent)
+	if err != nil || (vendors == nil && c.bia == nil) {
+		return
+	}
+	mac = append(net.HardwareAddr(nil), mac...)
+	eui := format.EUI(mac)
+	if eui == nil {
+		msg := fmt.Sprintf("Cannot constrain the hardware address of %s interfaces", format.Name)
+		err = UnsupportedAddressError{msg}
+		return
+	}
+	if vendors != nil {
+		var vendor Oui
+		vendor, err = randomVendor(vendors)
+		if err != nil {
+			return
+		}
+		var prefix net.HardwareAddr
+		var bits int
+		prefix, bits, err = vendor.Prefix()
+		if err != nil {
+			return
+		}
+		applyPrefix(eui, prefix, bits)
+	}
+	if c.bia != nil {
+		if *c.bia {
+			eui[0] &^= 2
+		} else {
+			eui[0] |= 2
+		}
+	}
+	return
+}
+
+// spoofMode spoofs with the registered strategy called mode
+func (h *Handle) spoofMode(name string, mode string, opts ...SpoofOption) (result SpoofResult, err error) {
+	strategy, err := LookupStrategy(mode)
+	if err != nil {
+		return
+	}
+	return h.Spoof(context.Background(), name, strategy, opts...)
+}
@@ -0,0 +1,70 @@
+package libmacouflage
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Spoof_1(t *testing.T) {
+	backend := newTestFakeBackend()
+	h := NewHandle(backend)
+	result, err := h.Spoof(context.Background(), "fake0", RandomStrategy(false),
+		WithDryRun(), WithBIA(true), WithDeviceType("oui_wireless_laptop"))
+	assert.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, byte(0), result.NewMac[0]&2)
+	assert.Equal(t, "oui_wireless_laptop", result.Device.DeviceType)
+	assert.True(t, result.Changed)
+	iface, _ := backend.Interface("fake0")
+	assert.Equal(t, result.OldMac, iface.HardwareAddr)
+	assert.Empty(t, backend.History("fake0"))
+	_, err = h.Spoof(context.Background(), "fake0", RandomStrategy(false),
+		WithVendorKeyword("no such vendor anywhere"))
+	assert.True(t, errors.Is(err, ErrNoVendor))
+}
+
+func Test_Spoof_2(t *testing.T) {
+	backend := newTestFakeBackend()
+	backend.AddDriverRule("e1000e", RejectLocallyAdministered)
+	h := NewHandle(backend)
+	result, err := h.Spoof(context.Background(), "fake0", RandomStrategy(false), WithAttempts(2))
+	assert.True(t, errors.Is(err, ErrAddressRejected))
+	assert.Equal(t, 2, result.Attempts)
+	result, err = h.Spoof(context.Background(), "fake0", RandomStrategy(false), WithBIA(true))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Attempts)
+	result, err = h.Spoof(context.Background(), "fake0", SpecificStrategy(net.HardwareAddr{2, 0, 0, 0, 0, 1}))
+	assert.True(t, errors.Is(err, ErrAddressRejected))
+	assert.Equal(t, 1, result.Attempts)
+	for _, attempts := range []int{0, -1} {
+		_, err = h.Spoof(context.Background(), "fake0", RandomStrategy(true), WithAttempts(attempts))
+		assert.True(t, errors.Is(err, ErrInvalidArgument))
+	}
+	assert.Len(t, backend.History("fake0"), 1)
+}
+
+func Test_Spoof_3(t *testing.T) {
+	backend := newTestFakeBackend()
+	backend.AddDriverRule("e1000e", RejectWhileUp)
+	iface, _ := backend.Interface("fake0")
+	iface.Up = true
+	backend.AddInterface(iface)
+	h := NewHandle(backend)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := h.Spoof(ctx, "fake0", RandomStrategy(false), WithAutoDown())
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Empty(t, backend.History("fake0"))
+	_, err = h.Spoof(context.Background(), "fake0", RandomStrategy(false))
+	assert.True(t, errors.Is(err, ErrInterfaceUp))
+	result, err := h.Spoof(context.Background(), "fake0", RandomStrategy(false), WithAutoDown())
+	assert.NoError(t, err)
+	iface, _ = backend.Interface("fake0")
+	assert.True(t, iface.Up)
+	assert.Equal(t, iface.HardwareAddr, result.NewMac)
+	assert.Equal(t, []net.HardwareAddr{result.NewMac}, backend.History("fake0"))
+}
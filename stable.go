@@ -0,0 +1,121 @@
+package libmacouflage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// PrefixSource is where the vendor bytes of a derived address come from.
+type PrefixSource int
+
+const (
+	// PrefixLocal derives the whole address, locally administered unicast
+	PrefixLocal PrefixSource = iota
+	// PrefixAny takes the OUI of a vendor of OuiDb
+	PrefixAny
+	// PrefixPopular takes the OUI of a popular vendor
+	PrefixPopular
+	// PrefixKeep keeps the OUI of the permanent address
+	PrefixKeep
+)
+
+// Labels keeping the addresses derived for different purposes from the same
+// secret apart
+const (
+	stableLabel = "libmacouflage stable"
+	epochLabel  = "libmacouflage epoch"
+)
+
+// StableStrategy derives the address from secret, the permanent address and
+// networkID, the way RFC 7217 derives IPv6 interface identifiers. An
+// interface gets the same address each time it joins a network, and its
+// addresses on different networks cannot be linked without the secret.
+// networkID is any identifier of the network, such as the ID of LinkNetwork
+// or an SSID.
+func StableStrategy(secret []byte, networkID []byte, prefix PrefixSource) Strategy {
+	secret = append([]byte(nil), secret...)
+	networkID = append([]byte(nil), networkID...)
+	return NewMode("Stable", "Set a MAC stable per network", "", ModeStable,
+		func(current net.HardwareAddr, permanent net.HardwareAddr) (net.HardwareAddr, error) {
+			return deriveMac(secret, stableLabel, current, permanent, networkID, prefix)
+		})
+}
+
+// deriveMac returns a copy of current whose EUI comes from
+// HMAC-SHA256(secret, label, permanent, data). With a vendor prefix the
+// vendor is picked from the digest too, the same inputs always give the same
+// address.
+func deriveMac(secret []byte, label string, current net.HardwareAddr, permanent net.HardwareAddr, data []byte, prefix PrefixSource) (mac net.HardwareAddr, err error) {
+	if len(secret) == 0 {
+		err = fmt.Errorf("Cannot derive an address from an empty secret")
+		return
+	}
+	if isZeroMac(permanent) {
+		err = fmt.Errorf("%w: derived addresses need the permanent MAC", ErrPermAddrUnsupported)
+		return
+	}
+	format, err := formatOf(current)
+	if err != nil {
+		return
+	}
+	mac = append(net.HardwareAddr(nil), current...)
+	eui := format.EUI(mac)
+	if eui == nil {
+		msg := fmt.Sprintf("Cannot derive the hardware address of %s interfaces", format.Name)
+		err = UnsupportedAddressError{msg}
+		return
+	}
+	digest := macDigest(secret, label, permanent, data)
+	copy(eui, digest)
+	var vendors []Oui
+	switch prefix {
+	case PrefixLocal:
+		eui[0] = eui[0]&^1 | 2
+		return
+	case PrefixAny:
+		vendors = OuiDb
+	case PrefixPopular:
+		vendors, err = FindAllPopularOuis()
+		if err != nil {
+			return
+		}
+	case PrefixKeep:
+		vendor := format.EUI(permanent)
+		if vendor == nil {
+			err = UnsupportedAddressError{"The permanent address has no vendor prefix to keep"}
+			return
+		}
+		applyPrefix(eui, vendor, vendorBits(vendor))
+		return
+	default:
+		err = fmt.Errorf("Unknown prefix source %d", prefix)
+		return
+	}
+	vendors = addressBlocks(vendors)
+	if len(vendors) == 0 {
+		err = NoVendorError{"No vendor to derive an address from", ""}
+		return
+	}
+	n := binary.BigEndian.Uint64(digest[len(digest)-8:]) % uint64(len(vendors))
+	oui, bits, err := vendors[n].Prefix()
+	if err != nil {
+		return
+	}
+	applyPrefix(eui, oui, bits)
+	return
+}
+
+// macDigest is the HMAC-SHA256 under secret of label, permanent and data,
+// each but the last preceded by its length so that no two inputs collide
+func macDigest(secret []byte, label string, permanent net.HardwareAddr, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte{byte(len(label))})
+	mac.Write([]byte(label))
+	mac.Write([]byte{byte(len(permanent))})
+	mac.Write(permanent)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,33 @@
+package libmacouflage
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StableStrategy_1(t *testing.T) {
+	current, _ := net.ParseMAC("00:11:22:33:44:55")
+	perm, _ := net.ParseMAC("00:11:22:aa:bb:cc")
+	secret := []byte("secret")
+	home, err := StableStrategy(secret, []byte("home"), PrefixLocal).Generate(current, perm)
+	assert.NoError(t, err)
+	again, _ := StableStrategy(secret, []byte("home"), PrefixLocal).Generate(current, perm)
+	assert.Equal(t, home, again)
+	assert.Equal(t, byte(2), home[0]&3)
+	work, _ := StableStrategy(secret, []byte("work"), PrefixLocal).Generate(current, perm)
+	assert.NotEqual(t, home, work)
+	other, _ := StableStrategy([]byte("other"), []byte("home"), PrefixLocal).Generate(current, perm)
+	assert.NotEqual(t, home, other)
+	popular, err := StableStrategy(secret, []byte("home"), PrefixPopular).Generate(current, perm)
+	assert.NoError(t, err)
+	oui, err := FindVendorByMac(popular.String())
+	assert.NoError(t, err)
+	assert.True(t, oui.Popular)
+	_, err = StableStrategy(secret, []byte("home"), PrefixAny).Generate(current, nil)
+	assert.True(t, errors.Is(err, ErrPermAddrUnsupported))
+	_, err = StableStrategy(nil, []byte("home"), PrefixAny).Generate(current, perm)
+	assert.Error(t, err)
+}
@@ -0,0 +1,217 @@
+package libmacouflage
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Strategy generates the new address of an interface from its current and
+// permanent ones. permanent is nil when it cannot be found. Name identifies
+// the strategy in the registry.
+type Strategy interface {
+	Name() string
+	Help() string
+	Generate(current net.HardwareAddr, permanent net.HardwareAddr) (net.HardwareAddr, error)
+}
+
+// generateFunc is the Generate method of a Mode
+type generateFunc func(current net.HardwareAddr, permanent net.HardwareAddr) (net.HardwareAddr, error)
+
+// strategies is the registry, in the order of registration
+var strategies = struct {
+	sync.Mutex
+	byName map[string]Strategy
+	order  []string
+}{byName: make(map[string]Strategy)}
+
+// NewMode returns a Strategy named flagLong that generates addresses with
+// generate. title and flagShort are for user interfaces.
+func NewMode(title string, help string, flagShort string, flagLong string, generate func(current net.HardwareAddr, permanent net.HardwareAddr) (net.HardwareAddr, error)) Mode {
+	return Mode{title, help, flagShort, flagLong, generate}
+}
+
+// Name is the long flag of the mode, such as "random".
+func (m Mode) Name() string {
+	return m.flagLong
+}
+
+// Title is the name of the mode for display, such as "Same Vendor".
+func (m Mode) Title() string {
+	return m.name
+}
+
+func (m Mode) Help() string {
+	return m.help
+}
+
+// Flags returns the macchanger style short and long options of the mode,
+// short is empty for modes macchanger does not have.
+func (m Mode) Flags() (short string, long string) {
+	return m.flagShort, m.flagLong
+}
+
+func (m Mode) Generate(current net.HardwareAddr, permanent net.HardwareAddr) (mac net.HardwareAddr, err error) {
+	if m.generate == nil {
+		err = fmt.Errorf("Mode %s cannot generate addresses", m.flagLong)
+		return
+	}
+	return m.generate(current, permanent)
+}
+
+// RegisterStrategy adds s to the registry under its name, which must not be
+// taken yet.
+func RegisterStrategy(s Strategy) (err error) {
+	name := strings.ToLower(s.Name())
+	if name == "" {
+		err = fmt.Errorf("Strategy without a name")
+		return
+	}
+	strategies.Lock()
+	defer strategies.Unlock()
+	if _, ok := strategies.byName[name]; ok {
+		err = fmt.Errorf("%w: %s", ErrStrategyExists, name)
+		return
+	}
+	strategies.byName[name] = s
+	strategies.order = append(strategies.order, name)
+	return
+}
+
+// LookupStrategy returns the registered strategy called name, ignoring case.
+func LookupStrategy(name string) (s Strategy, err error) {
+	strategies.Lock()
+	defer strategies.Unlock()
+	s, ok := strategies.byName[strings.ToLower(name)]
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrUnknownStrategy, name)
+	}
+	return
+}
+
+// Strategies returns the registered strategies, built-in ones first.
+func Strategies() (list []Strategy) {
+	strategies.Lock()
+	defer strategies.Unlock()
+	for _, name := range strategies.order {
+		list = append(list, strategies.byName[name])
+	}
+	return
+}
+
+// SpecificStrategy returns a strategy that always sets mac. The registered
+// "mac" mode has no address to set and only describes the mode.
+func SpecificStrategy(mac net.HardwareAddr) Strategy {
+	return NewMode("Specific", "Set the MAC "+mac.String(), "m", ModeSpecific,
+		func(current net.HardwareAddr, permanent net.HardwareAddr) (net.HardwareAddr, error) {
+			return append(net.HardwareAddr(nil), mac...), nil
+		})
+}
+
+// RandomStrategy randomizes the whole address, as a burned-in address if
+// bia is set and as a locally administered one otherwise.
+func RandomStrategy(bia bool) Strategy {
+	return NewMode("Random", "Set fully random MAC", "r", ModeRandom,
+		func(current net.HardwareAddr, permanent net.HardwareAddr) (mac net.HardwareAddr, err error) {
+			format, err := formatOf(current)
+			if err != nil {
+				return
+			}
+			return format.Randomize(current, 0, bia)
+		})
+}
+
+// SameVendorStrategy keeps the vendor bytes and randomizes the rest. The
+// vendor part is as long as the assignment in OuiDb, 24 bits for an unknown
+// vendor.
+func SameVendorStrategy(bia bool) Strategy {
+	return NewMode("Same Vendor", "Don't change the vendor bytes", "e", ModeEnding,
+		func(current net.HardwareAddr, permanent net.HardwareAddr) (mac net.HardwareAddr, err error) {
+			format, err := formatOf(current)
+			if err != nil {
+				return
+			}
+			eui := format.EUI(current)
+			if eui == nil {
+				return format.Randomize(current, 3, bia)
+			}
+			return format.RandomizePrefix(current, vendorBits(eui), bia)
+		})
+}
+
+func generateSameDeviceType(current net.HardwareAddr, permanent net.HardwareAddr) (mac net.HardwareAddr, err error) {
+	format, err := formatOf(current)
+	if err != nil {
+		return
+	}
+	eui := format.EUI(current)
+	if eui == nil {
+		msg := fmt.Sprintf("No vendor prefix in the hardware address of %s interfaces", format.Name)
+		err = UnsupportedAddressError{msg}
+		return
+	}
+	deviceType, err := FindDeviceTypeByMac(eui.String())
+	if err != nil {
+		return
+	}
+	vendors, err := FindAllVendorsByDeviceType(deviceType)
+	if err != nil {
+		return
+	}
+	if len(vendors) == 0 {
+		msg := fmt.Sprintf("No vendor found in OuiDb for device type: %s", deviceType)
+		err = NoVendorError{msg, ""}
+		return
+	}
+	vendor, err := randomVendor(vendors)
+	if err != nil {
+		return
+	}
+	return format.WithVendor(current, vendor.VendorPrefix)
+}
+
+func generateAnyDeviceType(current net.HardwareAddr, permanent net.HardwareAddr) (mac net.HardwareAddr, err error) {
+	format, err := formatOf(current)
+	if err != nil {
+		return
+	}
+	vendor, err := randomVendor(OuiDb)
+	if err != nil {
+		return
+	}
+	return format.WithVendor(current, vendor.VendorPrefix)
+}
+
+func generatePopular(current net.HardwareAddr, permanent net.HardwareAddr) (mac net.HardwareAddr, err error) {
+	format, err := formatOf(current)
+	if err != nil {
+		return
+	}
+	popular, err := FindAllPopularOuis()
+	if err != nil {
+		return
+	}
+	vendor, err := randomVendor(popular)
+	if err != nil {
+		return
+	}
+	return format.WithVendor(current, vendor.VendorPrefix)
+}
+
+// formatOf guesses the address format from the length of current, the
+// strategies are not told the interface type
+func formatOf(current net.HardwareAddr) (AddressFormat, error) {
+	return AddressFormatOf(Link{HardwareAddr: current})
+}
+
+func SpoofMacStrategy(name string, strategy Strategy) (result SpoofResult, err error) {
+	return defaultHandle().SpoofMacStrategy(name, strategy)
+}
+
+// SpoofMacStrategy sets the address strategy generates on the interface
+// called name.
+func (h *Handle) SpoofMacStrategy(name string, strategy Strategy) (result SpoofResult, err error) {
+	return h.Spoof(context.Background(), name, strategy)
+}
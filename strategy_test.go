@@ -0,0 +1,70 @@
+package libmacouflage
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// incrementStrategy adds one to the last byte of the current address
+type incrementStrategy struct{}
+
+func (incrementStrategy) Name() string { return "test-increment" }
+func (incrementStrategy) Help() string { return "Add one to the MAC" }
+
+func (incrementStrategy) Generate(current net.HardwareAddr, permanent net.HardwareAddr) (net.HardwareAddr, error) {
+	current[len(current)-1]++
+	return current, nil
+}
+
+func Test_Strategies_1(t *testing.T) {
+	var names []string
+	for _, s := range Strategies() {
+		names = append(names, s.Name())
+		assert.NotEmpty(t, s.Help())
+	}
+	assert.Equal(t, []string{ModeSpecific, ModeRandom, ModeEnding, ModeAnother, ModeAny, ModePopular}, names[:6])
+	s, err := LookupStrategy("Another")
+	assert.NoError(t, err)
+	short, long := s.(Mode).Flags()
+	assert.Equal(t, "a", short)
+	assert.Equal(t, "another", long)
+	assert.Equal(t, "Set random vendor MAC of the same kind", s.Help())
+	_, err = LookupStrategy("nonexistent")
+	assert.True(t, errors.Is(err, ErrUnknownStrategy))
+	err = RegisterStrategy(RandomStrategy(true))
+	assert.True(t, errors.Is(err, ErrStrategyExists))
+}
+
+func Test_Strategies_2(t *testing.T) {
+	s, err := LookupStrategy(ModeSpecific)
+	assert.NoError(t, err)
+	_, err = s.Generate(net.HardwareAddr{0, 0x11, 0x22, 0, 0, 1}, nil)
+	assert.Error(t, err)
+	backend := newTestFakeBackend()
+	h := NewHandle(backend)
+	mac := net.HardwareAddr{0x02, 0x11, 0x22, 0x33, 0x44, 0x55}
+	result, err := h.SpoofMacStrategy("fake0", SpecificStrategy(mac))
+	assert.NoError(t, err)
+	assert.Equal(t, ModeSpecific, result.Mode)
+	assert.Equal(t, mac, result.NewMac)
+	assert.True(t, result.Changed)
+}
+
+func Test_Strategies_3(t *testing.T) {
+	if _, err := LookupStrategy("test-increment"); err != nil {
+		assert.NoError(t, RegisterStrategy(incrementStrategy{}))
+	}
+	s, err := LookupStrategy("TEST-INCREMENT")
+	assert.NoError(t, err)
+	backend := newTestFakeBackend()
+	old, _ := backend.Interface("fake0")
+	result, err := NewHandle(backend).SpoofMacStrategy("fake0", s)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-increment", result.Mode)
+	assert.Equal(t, old.HardwareAddr, result.OldMac)
+	assert.Equal(t, old.HardwareAddr[5]+1, result.NewMac[5])
+	assert.Equal(t, old.HardwareAddr[:5], result.NewMac[:5])
+}